@@ -78,7 +78,8 @@ func (t *txImpl) Commit() error {
 	if t.writable {
 		return t.tx.Commit()
 	}
-	return nil
+	// read-only transactions must be rolled back to release them
+	return t.tx.Rollback()
 }
 
 func (t *txImpl) Rollback() error {
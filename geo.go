@@ -0,0 +1,36 @@
+package data
+
+import "errors"
+
+// EarthRadius is radius of the Earth in meters used to calculate distances.
+const EarthRadius = 6378100.0
+
+// Point is GeoJSON point, fields of this type are queried with q.Near and q.Within.
+type Point struct {
+	Type        string     `json:"type" bson:"type"`
+	Coordinates [2]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewPoint makes GeoJSON point with given coordinates.
+func NewPoint(lng, lat float64) *Point {
+	return &Point{
+		Type:        "Point",
+		Coordinates: [2]float64{lng, lat},
+	}
+}
+
+var errNoGeo = errors.New("store does not support geospatial index")
+
+type geoIndexer interface {
+	GeoIndex(collection string, fields ...string) error
+}
+
+// GeoIndex creates geospatial index of given point fields of collection.
+// Mongo requires it for q.Near conditions, postgresql calculates distances without index.
+func GeoIndex(s Store, collection string, fields ...string) error {
+	t, ok := s.(geoIndexer)
+	if !ok {
+		return errNoGeo
+	}
+	return t.GeoIndex(collection, fields...)
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/gocontrib/nosql/reflection"
+)
+
+// IDGenerator generates ids of inserted documents.
+type IDGenerator interface {
+	// NewID returns id of given document, empty id means the store assigns its native id.
+	NewID(doc interface{}) (string, error)
+}
+
+// IDGeneratorFunc is an adapter to use ordinary function as IDGenerator.
+type IDGeneratorFunc func(doc interface{}) (string, error)
+
+// NewID calls f(doc).
+func (f IDGeneratorFunc) NewID(doc interface{}) (string, error) {
+	return f(doc)
+}
+
+var (
+	// SequenceID uses native ids of the store (NextSequence in kv stores, SERIAL in postgresql, ObjectId in mongo).
+	SequenceID IDGenerator = &idGenerator{func(doc interface{}) (string, error) {
+		return "", nil
+	}}
+	// UUIDv4 generates random UUIDs.
+	UUIDv4 IDGenerator = &idGenerator{func(doc interface{}) (string, error) {
+		return NewUUIDv4()
+	}}
+	// UUIDv7 generates time-ordered UUIDs.
+	UUIDv7 IDGenerator = &idGenerator{func(doc interface{}) (string, error) {
+		return NewUUIDv7()
+	}}
+	// ULID generates time-ordered lexicographically sortable ids.
+	ULID IDGenerator = &idGenerator{func(doc interface{}) (string, error) {
+		return NewULID()
+	}}
+	// KSUID generates K-sortable ids.
+	KSUID IDGenerator = &idGenerator{func(doc interface{}) (string, error) {
+		return NewKSUID()
+	}}
+	// CallerID keeps id set by caller.
+	CallerID IDGenerator = &idGenerator{callerID}
+)
+
+// builtin generators are pointers to be comparable
+type idGenerator struct {
+	fn IDGeneratorFunc
+}
+
+func (g *idGenerator) NewID(doc interface{}) (string, error) {
+	return g.fn(doc)
+}
+
+// ErrNoID is returned when inserted document has no id set by caller.
+var ErrNoID = errors.New("document has no id")
+
+func callerID(doc interface{}) (string, error) {
+	var meta = reflection.GetMeta(doc)
+	if meta.GetID == nil {
+		return "", ErrNoID
+	}
+	id, ok := meta.GetID(doc).(string)
+	if !ok || len(id) == 0 {
+		return "", ErrNoID
+	}
+	return id, nil
+}
+
+// NewUUIDv4 returns random UUID (RFC 4122 version 4).
+func NewUUIDv4() (string, error) {
+	var u [16]byte
+	_, err := rand.Read(u[:])
+	if err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u), nil
+}
+
+// NewUUIDv7 returns UUID prefixed with unix time in milliseconds (RFC 9562 version 7).
+func NewUUIDv7() (string, error) {
+	var u [16]byte
+	_, err := rand.Read(u[6:])
+	if err != nil {
+		return "", err
+	}
+	putMillis(u[:6], time.Now())
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u), nil
+}
+
+func formatUUID(u [16]byte) string {
+	var s = hex.EncodeToString(u[:])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", s[0:8], s[8:12], s[12:16], s[16:20], s[20:])
+}
+
+func putMillis(b []byte, t time.Time) {
+	var ms = uint64(t.UnixNano() / int64(time.Millisecond))
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], ms)
+	copy(b, buf[2:])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns ULID, i.e. 48-bit unix time in milliseconds and 80 random bits
+// encoded with Crockford's base32.
+func NewULID() (string, error) {
+	var u [16]byte
+	_, err := rand.Read(u[6:])
+	if err != nil {
+		return "", err
+	}
+	putMillis(u[:6], time.Now())
+
+	// 128 bits are encoded to 26 characters of 5 bits, the first one holds 3 bits
+	var s [26]byte
+	var n = new(big.Int).SetBytes(u[:])
+	var mask = big.NewInt(31)
+	var d = new(big.Int)
+	for i := len(s) - 1; i >= 0; i-- {
+		d.And(n, mask)
+		s[i] = crockford[d.Int64()]
+		n.Rsh(n, 5)
+	}
+	return string(s[:]), nil
+}
+
+const (
+	base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// KSUID epoch starts at 2014-05-13 16:53:20 UTC
+	ksuidEpoch = 1400000000
+	ksuidLen   = 27
+)
+
+// NewKSUID returns KSUID, i.e. 32-bit timestamp in seconds and 128 random bits encoded with base62.
+func NewKSUID() (string, error) {
+	var u [20]byte
+	_, err := rand.Read(u[4:])
+	if err != nil {
+		return "", err
+	}
+	binary.BigEndian.PutUint32(u[:4], uint32(time.Now().Unix()-ksuidEpoch))
+
+	var s [ksuidLen]byte
+	var n = new(big.Int).SetBytes(u[:])
+	var base = big.NewInt(62)
+	var d = new(big.Int)
+	for i := len(s) - 1; i >= 0; i-- {
+		n.DivMod(n, base, d)
+		s[i] = base62[d.Int64()]
+	}
+	return string(s[:]), nil
+}
+
+// IDGenerators holds id generators of store and its collections.
+// Stores with pluggable id generation embed it.
+type IDGenerators struct {
+	mu         sync.Mutex
+	def        IDGenerator
+	collection map[string]IDGenerator
+}
+
+// SetIDGenerator sets id generator of given collections, by default of the whole store.
+func (g *IDGenerators) SetIDGenerator(gen IDGenerator, collections ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(collections) == 0 {
+		g.def = gen
+		return
+	}
+	if g.collection == nil {
+		g.collection = make(map[string]IDGenerator)
+	}
+	for _, name := range collections {
+		g.collection[name] = gen
+	}
+}
+
+// IDGenerator returns id generator of given collection.
+func (g *IDGenerators) IDGenerator(collection string) IDGenerator {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gen, ok := g.collection[collection]; ok && gen != nil {
+		return gen
+	}
+	if g.def != nil {
+		return g.def
+	}
+	return SequenceID
+}
+
+// NativeID determines whether documents of given collection get native ids of the store.
+func (g *IDGenerators) NativeID(collection string) bool {
+	return g.IDGenerator(collection) == SequenceID
+}
+
+// NewID generates id of given document, empty id means the store assigns its native id.
+func (g *IDGenerators) NewID(collection string, doc interface{}) (string, error) {
+	return g.IDGenerator(collection).NewID(doc)
+}
+
+type idGeneratorSetter interface {
+	SetIDGenerator(gen IDGenerator, collections ...string)
+}
+
+var errNoIDGenerators = errors.New("store does not support id generators")
+
+// SetIDGenerator sets id generator of given collections of the store, by default of the whole store.
+func SetIDGenerator(s Store, gen IDGenerator, collections ...string) error {
+	t, ok := s.(idGeneratorSetter)
+	if !ok {
+		return errNoIDGenerators
+	}
+	t.SetIDGenerator(gen, collections...)
+	return nil
+}
@@ -60,7 +60,7 @@ func (c *collection) Count() (int64, error) {
 
 // Insert given documents to the collection.
 func (c *collection) Insert(docs ...interface{}) error {
-	var tx, err = c.db.Begin(true)
+	var tx, err = c.store.begin()
 	if err != nil {
 		return err
 	}
@@ -78,9 +78,9 @@ func (c *collection) Insert(docs ...interface{}) error {
 	var now = time.Now().UTC()
 
 	for _, doc := range docs {
-		id, err := bucket.NextSequence()
+		id, err := c.newID(bucket, doc)
 		if err != nil {
-			return debug.Err("bucket.NextSequence", err)
+			return err
 		}
 
 		var meta = reflection.GetMeta(doc)
@@ -93,12 +93,18 @@ func (c *collection) Insert(docs ...interface{}) error {
 			return err
 		}
 
-		err = bucket.Set([]byte(id), json)
+		err = bucket.Set(idKey(id), json)
+		if err != nil {
+			return err
+		}
+
+		var data map[string]interface{}
+		err = unmarshal(json, &data)
 		if err != nil {
 			return err
 		}
 
-		err = c.idx.update(tx, id, doc, nil)
+		err = c.idx.update(tx, id, doc, data, nil)
 		if err != nil {
 			return debug.Err("index.Update", err)
 		}
@@ -107,6 +113,29 @@ func (c *collection) Insert(docs ...interface{}) error {
 	return tx.Commit()
 }
 
+// newID generates id of inserted document.
+func (c *collection) newID(bucket Bucket, doc interface{}) (string, error) {
+	id, err := c.store.NewID(c.name, doc)
+	if err != nil {
+		return "", debug.Err("NewID", err)
+	}
+	if len(id) == 0 {
+		id, err = bucket.NextSequence()
+		if err != nil {
+			return "", debug.Err("bucket.NextSequence", err)
+		}
+		return id, nil
+	}
+	v, err := bucket.Get(idKey(id))
+	if err != nil {
+		return "", err
+	}
+	if v != nil {
+		return "", errDuplicateID
+	}
+	return id, nil
+}
+
 // Gets one result by id.
 func (c *collection) Get(id string, result interface{}) error {
 	var tx, err = c.db.Begin(false)
@@ -124,7 +153,7 @@ func (c *collection) Get(id string, result interface{}) error {
 		return errNotFound
 	}
 
-	value, err := bucket.Get([]byte(id))
+	value, err := bucket.Get(idKey(id))
 	if value == nil || err != nil {
 		if err != nil {
 			return err
@@ -161,7 +190,7 @@ func (c *collection) Update(selector interface{}, doc interface{}) error {
 
 	var id, ok = selector.(string)
 	if ok {
-		tx, err := c.db.Begin(true)
+		tx, err := c.store.begin()
 		if err != nil {
 			return err
 		}
@@ -176,7 +205,7 @@ func (c *collection) Update(selector interface{}, doc interface{}) error {
 			return errNotFound
 		}
 
-		err = c.update(tx, bucket, doc, []byte(id), json)
+		err = c.update(tx, bucket, doc, idKey(id), json)
 		if err != nil {
 			return err
 		}
@@ -207,25 +236,29 @@ func (c *collection) update(tx Tx, bucket Bucket, doc interface{}, k, v []byte)
 		return errNotFound
 	}
 
-	err = bucket.Set(k, v)
+	var data, prev map[string]interface{}
+	err = unmarshal(old, &prev)
+	if err != nil {
+		return err
+	}
+	err = unmarshal(v, &data)
 	if err != nil {
 		return err
 	}
 
-	var data map[string]interface{}
-	err = unmarshal(old, &data)
+	err = bucket.Set(k, v)
 	if err != nil {
 		return err
 	}
 
-	return c.idx.update(tx, string(k), doc, data)
+	return c.idx.update(tx, keyID(k), doc, data, prev)
 }
 
 // Delete documents that match given filter.
 func (c *collection) Delete(selector interface{}) error {
 	var id, ok = selector.(string)
 	if ok {
-		tx, err := c.db.Begin(true)
+		tx, err := c.store.begin()
 		if err != nil {
 			return err
 		}
@@ -240,7 +273,7 @@ func (c *collection) Delete(selector interface{}) error {
 			return errNotFound
 		}
 
-		err = c.delete(tx, bucket, []byte(id), nil)
+		err = c.delete(tx, bucket, idKey(id), nil)
 		if err != nil {
 			return err
 		}
@@ -287,7 +320,7 @@ func (c *collection) delete(tx Tx, bucket Bucket, k, v []byte) error {
 		return err
 	}
 
-	return c.idx.clean(tx, string(k), data)
+	return c.idx.clean(tx, keyID(k), data)
 }
 
 func (c *collection) cursor(selector interface{}) (*cursor, error) {
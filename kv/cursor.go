@@ -1,5 +1,7 @@
 package kv
 
+import "io"
+
 type cursor struct {
 	view   *view
 	tx     Tx
@@ -20,6 +22,11 @@ func (c *cursor) Close() error {
 
 	c.closed = true
 
+	// release resources of iterator (e.g. temporary files of sort)
+	if it, ok := c.iter.(io.Closer); ok {
+		it.Close()
+	}
+
 	var err = c.tx.Commit()
 	if err != nil {
 		return err
@@ -47,6 +54,9 @@ func (c *cursor) next() bool {
 	ok, err := c.iter.Next()
 	if err != nil {
 		c.closed = true
+		if it, ok := c.iter.(io.Closer); ok {
+			it.Close()
+		}
 		c.tx.Rollback()
 		return false
 	}
@@ -1,6 +1,11 @@
 package kv
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
 	"github.com/gocontrib/nosql/q"
 	"github.com/gocontrib/nosql/util"
 )
@@ -20,6 +25,19 @@ func MakeFilterFn(filter []interface{}) FilterFn {
 	return and(conds)
 }
 
+// normalize returns top-level conditions of canonical filter (see q.Normalize),
+// so lookup could use indexes more often. It returns false if filter matches no documents.
+func normalize(filter []interface{}) ([]interface{}, bool) {
+	var f, ok = q.Normalize(q.And(filter))
+	switch t := f.(type) {
+	case nil:
+		return nil, ok
+	case q.And:
+		return t, true
+	}
+	return []interface{}{f}, true
+}
+
 func condition(c interface{}) FilterFn {
 	switch t := c.(type) {
 	case q.Not:
@@ -42,9 +60,17 @@ func condition(c interface{}) FilterFn {
 			return conds[0]
 		}
 		return or(conds)
+	case idsMatch:
+		return func(k string, v map[string]interface{}) bool {
+			return t.ids.has(keyID([]byte(k)))
+		}
+	case q.VectorSearch:
+		// vector search is allowed only at top level of query
+		panic("invalid query")
 	case q.M:
 		if len(t) == 0 {
-			panic("invalid query")
+			// empty condition of array element
+			return func(k string, v map[string]interface{}) bool { return true }
 		}
 		var conds []FilterFn
 		for k, v := range t {
@@ -62,66 +88,275 @@ func fieldFilter(name string, value interface{}) FilterFn {
 	if name == "_id" {
 		name = "id"
 	}
+	if op, ok := value.(q.Op); ok && op.Kind == q.OpExists {
+		var exists, _ = op.Value.(bool)
+		return func(k string, v map[string]interface{}) bool {
+			var _, ok = lookupPath(v, name)
+			return ok == exists
+		}
+	}
+	return field(name, valueFilter(value))
+}
+
+// valueFilter makes predicate of field value. Conditions on array fields
+// are satisfied by any of array elements as in mongo.
+func valueFilter(value interface{}) func(interface{}) bool {
 	switch t := value.(type) {
 	case q.In:
-		return field(name, func(v interface{}) bool {
+		return func(v interface{}) bool {
 			for _, i := range t {
-				if eq(v, i) {
+				if eqOrContains(v, i) {
 					return true
 				}
 			}
 			return false
-		})
+		}
 	case q.NotIn:
-		return field(name, func(v interface{}) bool {
+		return func(v interface{}) bool {
+			for _, i := range t {
+				if eqOrContains(v, i) {
+					return false
+				}
+			}
+			return true
+		}
+	case q.All:
+		return func(v interface{}) bool {
+			if _, ok := v.([]interface{}); !ok {
+				return false
+			}
 			for _, i := range t {
-				if eq(v, i) {
+				if !eqOrContains(v, i) {
 					return false
 				}
 			}
 			return true
-		})
+		}
+	case q.Size:
+		return func(v interface{}) bool {
+			a, ok := v.([]interface{})
+			return ok && len(a) == int(t)
+		}
+	case q.GeoNear:
+		return geoFilter(t)
+	case q.GeoWithin:
+		return geoFilter(t)
+	case q.ElemMatch:
+		var p = elemFilter(t.Condition)
+		return func(v interface{}) bool {
+			a, ok := v.([]interface{})
+			if !ok {
+				return false
+			}
+			for _, e := range a {
+				if p(e) {
+					return true
+				}
+			}
+			return false
+		}
 	case q.Op:
 		var val = t.Value
 		switch t.Kind {
 		case q.OpLT:
-			return field(name, func(v interface{}) bool {
-				return lt(v, val)
-			})
+			return func(v interface{}) bool {
+				return anyElem(v, func(e interface{}) bool { return lt(e, val) })
+			}
 		case q.OpLTE:
-			return field(name, func(v interface{}) bool {
-				return lte(v, val)
-			})
+			return func(v interface{}) bool {
+				return anyElem(v, func(e interface{}) bool { return lte(e, val) })
+			}
 		case q.OpGT:
-			return field(name, func(v interface{}) bool {
-				return gt(v, val)
-			})
+			return func(v interface{}) bool {
+				return anyElem(v, func(e interface{}) bool { return gt(e, val) })
+			}
 		case q.OpGTE:
-			return field(name, func(v interface{}) bool {
-				return gte(v, val)
-			})
+			return func(v interface{}) bool {
+				return anyElem(v, func(e interface{}) bool { return gte(e, val) })
+			}
 		case q.OpNE:
-			return field(name, func(v interface{}) bool {
-				return !eq(v, val)
-			})
+			return func(v interface{}) bool {
+				return !eqOrContains(v, val)
+			}
+		case q.OpRegex:
+			var re, err = compileRegex(val)
+			if err != nil {
+				// invalid patterns are reported by checkRegex
+				return func(v interface{}) bool { return false }
+			}
+			return func(v interface{}) bool {
+				return anyString(v, re.MatchString)
+			}
+		case q.OpPrefix:
+			var prefix, _ = val.(string)
+			return func(v interface{}) bool {
+				return anyString(v, func(s string) bool { return strings.HasPrefix(s, prefix) })
+			}
+		case q.OpIEqual:
+			var str, _ = val.(string)
+			return func(v interface{}) bool {
+				return anyString(v, func(s string) bool { return strings.EqualFold(s, str) })
+			}
 		default:
 			panic("invalid op")
 		}
 	default:
-		return field(name, func(v interface{}) bool {
-			return eq(v, value)
-		})
+		return func(v interface{}) bool {
+			return eqOrContains(v, value)
+		}
+	}
+}
+
+// elemFilter makes predicate of array element for ElemMatch condition.
+func elemFilter(c interface{}) func(interface{}) bool {
+	switch t := c.(type) {
+	case q.Not:
+		var p = elemFilter(t.Condition)
+		return func(e interface{}) bool {
+			return !p(e)
+		}
+	case q.And:
+		var list []func(interface{}) bool
+		for _, i := range t {
+			list = append(list, elemFilter(i))
+		}
+		return func(e interface{}) bool {
+			for _, p := range list {
+				if !p(e) {
+					return false
+				}
+			}
+			return true
+		}
+	case q.Or:
+		var list []func(interface{}) bool
+		for _, i := range t {
+			list = append(list, elemFilter(i))
+		}
+		return func(e interface{}) bool {
+			for _, p := range list {
+				if p(e) {
+					return true
+				}
+			}
+			return false
+		}
+	case q.M:
+		var f = condition(t)
+		return func(e interface{}) bool {
+			m, ok := e.(map[string]interface{})
+			return ok && f("", m)
+		}
+	default:
+		return valueFilter(c)
+	}
+}
+
+// anyElem applies predicate to given value or to elements of array value.
+func anyElem(v interface{}, p func(interface{}) bool) bool {
+	a, ok := v.([]interface{})
+	if !ok {
+		return p(v)
+	}
+	for _, e := range a {
+		if p(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyString applies predicate to given string value or to string elements of array value.
+func anyString(v interface{}, p func(string) bool) bool {
+	return anyElem(v, func(e interface{}) bool {
+		s, ok := e.(string)
+		return ok && p(s)
+	})
+}
+
+// compileRegex compiles value of regex condition.
+func compileRegex(val interface{}) (*regexp.Regexp, error) {
+	var pattern string
+	switch t := val.(type) {
+	case q.RegexValue:
+		pattern = t.Pattern
+		if len(t.Flags) > 0 {
+			for _, f := range t.Flags {
+				if !strings.ContainsRune("ims", f) {
+					return nil, fmt.Errorf("invalid regular expression flags %q", t.Flags)
+				}
+			}
+			pattern = "(?" + t.Flags + ")" + pattern
+		}
+	case string:
+		pattern = t
+	default:
+		return nil, fmt.Errorf("invalid regular expression %v", val)
+	}
+	return regexp.Compile(pattern)
+}
+
+// checkRegex compiles patterns of regex conditions of filter, so invalid patterns
+// are returned as errors of query instead of matching nothing.
+func checkRegex(c interface{}) error {
+	switch t := c.(type) {
+	case []interface{}:
+		return checkRegexList(t)
+	case q.And:
+		return checkRegexList(t)
+	case q.Or:
+		return checkRegexList(t)
+	case q.Not:
+		return checkRegex(t.Condition)
+	case q.ElemMatch:
+		return checkRegex(t.Condition)
+	case q.M:
+		for _, v := range t {
+			if err := checkRegex(v); err != nil {
+				return err
+			}
+		}
+	case q.Op:
+		if t.Kind == q.OpRegex {
+			_, err := compileRegex(t.Value)
+			return err
+		}
+		return checkRegex(t.Value)
 	}
 	return nil
 }
 
+func checkRegexList(list []interface{}) error {
+	for _, c := range list {
+		if err := checkRegex(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eqOrContains determines whether value equals to given one
+// or, if value is array, whether it contains given one.
+func eqOrContains(v, val interface{}) bool {
+	if eq(v, val) {
+		return true
+	}
+	a, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, e := range a {
+		if eq(e, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// field makes filter of field value, missing fields are passed as nil values.
 func field(name string, p func(interface{}) bool) FilterFn {
 	return func(k string, v map[string]interface{}) bool {
-		var e, ok = v[name]
-		if !ok {
-			return false
-		}
-		return p(e)
+		return p(pathValue(v, name))
 	}
 }
 
@@ -154,21 +389,49 @@ func or(conds []FilterFn) FilterFn {
 }
 
 func eq(a, b interface{}) bool {
+	if x, ok := a.([]interface{}); ok {
+		var y = toArray(b)
+		if y == nil || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !eq(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
 	return util.Compare(a, b) == 0
 }
 
+// toArray converts slice value to array of interfaces, returns nil for other values.
+func toArray(v interface{}) []interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return a
+	}
+	var r = reflect.ValueOf(v)
+	if r.Kind() != reflect.Slice {
+		return nil
+	}
+	var a = make([]interface{}, r.Len())
+	for i := range a {
+		a[i] = r.Index(i).Interface()
+	}
+	return a
+}
+
 func lt(a, b interface{}) bool {
-	return util.Compare(a, b) < 0
+	return a != nil && b != nil && util.Compare(a, b) < 0
 }
 
 func lte(a, b interface{}) bool {
-	return util.Compare(a, b) <= 0
+	return a != nil && b != nil && util.Compare(a, b) <= 0
 }
 
 func gt(a, b interface{}) bool {
-	return util.Compare(a, b) > 0
+	return a != nil && b != nil && util.Compare(a, b) > 0
 }
 
 func gte(a, b interface{}) bool {
-	return util.Compare(a, b) >= 0
+	return a != nil && b != nil && util.Compare(a, b) >= 0
 }
@@ -7,13 +7,11 @@ import (
 )
 
 // FilterIter creates filtered iterator.
-func FilterIter(cursor Cursor, filter []interface{}, limit, skip int64) Iter {
+func FilterIter(cursor Cursor, filter []interface{}) Iter {
 	return &filterIter{
 		cursor:    cursor,
 		filterFn:  MakeFilterFn(filter),
 		hasFilter: len(filter) > 0,
-		limit:     limit,
-		skip:      skip,
 	}
 }
 
@@ -21,9 +19,6 @@ type filterIter struct {
 	cursor      Cursor
 	filterFn    FilterFn
 	hasFilter   bool
-	limit       int64
-	skip        int64
-	count       int64
 	initialized bool
 	closed      bool
 	// current pair
@@ -44,21 +39,11 @@ func (it *filterIter) Next() (bool, error) {
 		return false, nil
 	}
 
-	// support limit
-	if it.limit > 0 && it.count >= it.limit {
-		it.close()
-		return false, nil
-	}
-
 	var k []byte
 	var v []byte
 	if !it.initialized {
 		it.initialized = true
-		tk, tv, err := it.init()
-		if err != nil {
-			return false, err
-		}
-		k, v = tk, tv
+		k, v = it.cursor.First()
 	} else {
 		k, v = it.cursor.Next()
 	}
@@ -85,27 +70,6 @@ func (it *filterIter) Next() (bool, error) {
 	return true, nil
 }
 
-func (it *filterIter) init() ([]byte, []byte, error) {
-	k, v := it.cursor.First()
-	// skip first n docs
-	if it.skip > 0 {
-		var skip = it.skip
-		for k != nil && skip > 0 {
-			var err = it.filter(&k, &v)
-			if err != nil {
-				debug.Err("filter", err)
-				return nil, nil, err
-			}
-			if k == nil {
-				break
-			}
-			k, v = it.cursor.Next()
-			skip--
-		}
-	}
-	return k, v, nil
-}
-
 func (it *filterIter) filter(k, v *[]byte) error {
 	if it.hasFilter {
 		for *k != nil {
@@ -123,9 +87,6 @@ func (it *filterIter) filter(k, v *[]byte) error {
 			*v = tv
 		}
 	}
-	if *k != nil {
-		it.count++
-	}
 	return nil
 }
 
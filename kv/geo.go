@@ -0,0 +1,513 @@
+package kv
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/q"
+)
+
+// Geospatial index of point field holds one entry per document with point value.
+// Key of entry is geohash of point and document id separated by zero byte
+// (see idxKey). Points of geohash cell share prefix of keys, so documents
+// in area are found by seeking prefixes of cells covering the area.
+
+const (
+	// precision of indexed geohashes (cells of few centimeters)
+	geoPrecision = 12
+	// maximal number of cells covering area of query
+	geoMaxCells = 32
+)
+
+// geoName returns name of geospatial index bucket for given collection field.
+func geoName(collection, field string) string {
+	return "geo_" + collection + "_" + field
+}
+
+func geoRegistryKey(collection, field string) []byte {
+	return []byte(collection + ".geo." + field)
+}
+
+// GeoIndex registers geospatial index of given point fields of collection and builds it.
+func (s *store) GeoIndex(collection string, fields ...string) error {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Bucket(collection, true)
+	if err != nil {
+		return err
+	}
+
+	err = s.migrate(tx, collection)
+	if err != nil {
+		return err
+	}
+
+	meta, err := tx.Bucket(metaBucket, true)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		err = meta.Set(geoRegistryKey(collection, f), []byte(f))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = buildGeo(tx, collection, fields)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// geoFields returns point fields of collection with geospatial index.
+func geoFields(tx Tx, collection string) ([]string, error) {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return nil, err
+	}
+
+	var prefix = geoRegistryKey(collection, "")
+	var fields []string
+	var c = meta.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		fields = append(fields, string(v))
+	}
+	return fields, nil
+}
+
+// hasGeoIdx determines whether given field of collection has geospatial index.
+func hasGeoIdx(tx Tx, collection, field string) bool {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return false
+	}
+	v, err := meta.Get(geoRegistryKey(collection, field))
+	return v != nil && err == nil
+}
+
+// buildGeo rebuilds geospatial indexes of given fields.
+func buildGeo(tx Tx, collection string, fields []string) error {
+	bucket, err := tx.Bucket(collection, false)
+	if bucket == nil || err != nil {
+		if err != nil {
+			return err
+		}
+		return errNotFound
+	}
+
+	for _, f := range fields {
+		idx, err := tx.Bucket(geoName(collection, f), true)
+		if err != nil {
+			return err
+		}
+
+		err = clearBucket(idx)
+		if err != nil {
+			return err
+		}
+
+		var c = bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var data map[string]interface{}
+			err = unmarshal(v, &data)
+			if err != nil {
+				return err
+			}
+			if h, ok := geoKey(pathValue(data, f)); ok {
+				var id = keyID(k)
+				err = idx.Set(idxKey(h, id), []byte(id))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// updateGeo maintains geospatial indexes of document, old is previous version of document.
+func (c *collectionIdx) updateGeo(tx Tx, id string, data, old map[string]interface{}) error {
+	fields, err := geoFields(tx, c.name)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		idx, err := tx.Bucket(geoName(c.name, f), true)
+		if err != nil {
+			return err
+		}
+		if old != nil {
+			if h, ok := geoKey(pathValue(old, f)); ok {
+				err = idx.Delete(idxKey(h, id))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if h, ok := geoKey(pathValue(data, f)); ok {
+			err = idx.Set(idxKey(h, id), []byte(id))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cleanGeo removes document from geospatial indexes.
+func (c *collectionIdx) cleanGeo(tx Tx, id string, data map[string]interface{}) error {
+	fields, err := geoFields(tx, c.name)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		h, ok := geoKey(pathValue(data, f))
+		if !ok {
+			continue
+		}
+		idx, err := tx.Bucket(geoName(c.name, f), false)
+		if err != nil {
+			return err
+		}
+		if idx == nil {
+			continue
+		}
+		err = idx.Delete(idxKey(h, id))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// geoKey returns indexed geohash of GeoJSON point.
+func geoKey(v interface{}) (string, bool) {
+	lng, lat, ok := geoPoint(v)
+	if !ok {
+		return "", false
+	}
+	return geohash(lng, lat, geoPrecision), true
+}
+
+// geoScan returns ids of documents with points in cells covering given area.
+func geoScan(idx Bucket, area geoRect) keys {
+	var list keys
+	var c = idx.Cursor()
+	for _, cell := range area.cells() {
+		var prefix = []byte(cell)
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			list = append(list, string(idxEntryID(k)))
+		}
+	}
+	return list
+}
+
+// geoArea returns bounding rectangle of area of geospatial condition.
+func geoArea(cond interface{}) geoRect {
+	switch t := cond.(type) {
+	case q.GeoNear:
+		if t.MaxDistance <= 0 {
+			return worldRect
+		}
+		var dlat = t.MaxDistance / data.EarthRadius * 180 / math.Pi
+		var r = geoRect{t.Lng, t.Lat - dlat, t.Lng, t.Lat + dlat}
+		var cos = math.Cos(t.Lat * math.Pi / 180)
+		if cos < 1e-6 || r.minLat < -90 || r.maxLat > 90 {
+			// around the pole
+			r.minLng, r.maxLng = -180, 180
+		} else {
+			var dlng = dlat / cos
+			r.minLng, r.maxLng = t.Lng-dlng, t.Lng+dlng
+		}
+		return r.clamp()
+	case q.GeoWithin:
+		switch s := t.Shape.(type) {
+		case q.Box:
+			return geoRect{s.MinLng, s.MinLat, s.MaxLng, s.MaxLat}.clamp()
+		case q.Polygon:
+			var r = geoRect{180, 90, -180, -90}
+			for _, p := range s {
+				r.minLng = math.Min(r.minLng, p[0])
+				r.minLat = math.Min(r.minLat, p[1])
+				r.maxLng = math.Max(r.maxLng, p[0])
+				r.maxLat = math.Max(r.maxLat, p[1])
+			}
+			return r.clamp()
+		}
+	}
+	panic("invalid query")
+}
+
+// geoRect is rectangle in lng/lat plane.
+type geoRect struct {
+	minLng, minLat, maxLng, maxLat float64
+}
+
+var worldRect = geoRect{-180, -90, 180, 90}
+
+func (r geoRect) clamp() geoRect {
+	r.minLng = math.Max(r.minLng, -180)
+	r.minLat = math.Max(r.minLat, -90)
+	r.maxLng = math.Min(r.maxLng, 180)
+	r.maxLat = math.Min(r.maxLat, 90)
+	return r
+}
+
+// cells returns sorted geohashes of cells covering rectangle,
+// precision of cells is the finest one needing at most geoMaxCells cells.
+func (r geoRect) cells() []string {
+	if r.minLng > r.maxLng || r.minLat > r.maxLat {
+		return nil
+	}
+	var best []string
+	for p := 1; p <= geoPrecision; p++ {
+		var w, h = geoCellSize(p)
+		var i0, i1 = math.Floor((r.minLng + 180) / w), math.Floor((r.maxLng + 180) / w)
+		var j0, j1 = math.Floor((r.minLat + 90) / h), math.Floor((r.maxLat + 90) / h)
+		if (i1-i0+1)*(j1-j0+1) > geoMaxCells {
+			break
+		}
+		var cells []string
+		for i := i0; i <= i1; i++ {
+			for j := j0; j <= j1; j++ {
+				// center of cell
+				var lng = math.Min(-180+(i+0.5)*w, 180)
+				var lat = math.Min(-90+(j+0.5)*h, 90)
+				cells = append(cells, geohash(lng, lat, p))
+			}
+		}
+		best = cells
+	}
+	sort.Strings(best)
+	return best
+}
+
+// geoCellSize returns width and height in degrees of geohash cells of given precision.
+func geoCellSize(precision int) (float64, float64) {
+	var bits = uint(precision * 5)
+	var lngBits = (bits + 1) / 2
+	var latBits = bits / 2
+	return 360 / float64(uint64(1)<<lngBits), 180 / float64(uint64(1)<<latBits)
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geohash encodes point to geohash of given precision.
+func geohash(lng, lat float64, precision int) string {
+	var minLng, maxLng = -180.0, 180.0
+	var minLat, maxLat = -90.0, 90.0
+	var hash = make([]byte, 0, precision)
+	var even = true
+	var bit, ch = 0, 0
+	for len(hash) < precision {
+		if even {
+			var mid = (minLng + maxLng) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				minLng = mid
+			} else {
+				ch = ch << 1
+				maxLng = mid
+			}
+		} else {
+			var mid = (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch = ch << 1
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// geoPoint returns coordinates of GeoJSON point.
+func geoPoint(v interface{}) (float64, float64, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || m["type"] != "Point" {
+		return 0, 0, false
+	}
+	c, ok := m["coordinates"].([]interface{})
+	if !ok || len(c) != 2 {
+		return 0, 0, false
+	}
+	lng, ok1 := c[0].(float64)
+	lat, ok2 := c[1].(float64)
+	return lng, lat, ok1 && ok2
+}
+
+// geoDistance returns distance in meters between points using haversine formula.
+func geoDistance(lng1, lat1, lng2, lat2 float64) float64 {
+	var rad = math.Pi / 180
+	var dlat = (lat2 - lat1) * rad
+	var dlng = (lng2 - lng1) * rad
+	var a = math.Pow(math.Sin(dlat/2), 2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin(dlng/2), 2)
+	return 2 * data.EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// geoFilter makes predicate of GeoJSON point for geospatial condition.
+func geoFilter(cond interface{}) func(interface{}) bool {
+	var in func(lng, lat float64) bool
+	switch t := cond.(type) {
+	case q.GeoNear:
+		in = func(lng, lat float64) bool {
+			return t.MaxDistance <= 0 || geoDistance(t.Lng, t.Lat, lng, lat) <= t.MaxDistance
+		}
+	case q.GeoWithin:
+		switch s := t.Shape.(type) {
+		case q.Box:
+			in = func(lng, lat float64) bool {
+				return lng >= s.MinLng && lng <= s.MaxLng && lat >= s.MinLat && lat <= s.MaxLat
+			}
+		case q.Polygon:
+			in = func(lng, lat float64) bool {
+				return inPolygon(s, lng, lat)
+			}
+		default:
+			panic("invalid query")
+		}
+	}
+	return func(v interface{}) bool {
+		lng, lat, ok := geoPoint(v)
+		return ok && in(lng, lat)
+	}
+}
+
+// inPolygon determines whether point is inside of polygon using ray casting.
+func inPolygon(p q.Polygon, lng, lat float64) bool {
+	var in = false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		var a, b = p[i], p[j]
+		if (a[1] > lat) != (b[1] > lat) && lng < (b[0]-a[0])*(lat-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// nearCondition returns field and condition of required GeoNear of filter.
+func nearCondition(filter []interface{}) (string, q.GeoNear, bool) {
+	for _, c := range filter {
+		switch t := c.(type) {
+		case q.M:
+			for k, v := range t {
+				if near, ok := v.(q.GeoNear); ok {
+					return k, near, true
+				}
+			}
+		case q.And:
+			if k, near, ok := nearCondition(t); ok {
+				return k, near, true
+			}
+		}
+	}
+	return "", q.GeoNear{}, false
+}
+
+// NearIter creates iterator ordering results of given one by distance of point field to given location.
+func NearIter(iter Iter, field string, lng, lat float64) Iter {
+	return &nearIter{
+		iter:  iter,
+		field: field,
+		lng:   lng,
+		lat:   lat,
+	}
+}
+
+type nearIter struct {
+	iter        Iter
+	field       string
+	lng         float64
+	lat         float64
+	initialized bool
+	data        []*pair
+	dist        []float64
+	idx         int
+	cur         *pair
+}
+
+func (it *nearIter) Key() []byte {
+	return it.cur.key
+}
+
+func (it *nearIter) Value() []byte {
+	return it.cur.value
+}
+
+func (it *nearIter) Next() (bool, error) {
+	if !it.initialized {
+		it.initialized = true
+		var err = it.load()
+		if err != nil {
+			return false, err
+		}
+	}
+	if it.idx >= len(it.data) {
+		it.cur = nil
+		return false, nil
+	}
+	it.cur = it.data[it.idx]
+	it.idx++
+	return true, nil
+}
+
+func (it *nearIter) load() error {
+	for {
+		ok, err := it.iter.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		var v = append([]byte{}, it.iter.Value()...)
+		var doc map[string]interface{}
+		err = unmarshal(v, &doc)
+		if err != nil {
+			return err
+		}
+		var d = math.Inf(1)
+		if lng, lat, ok := geoPoint(pathValue(doc, it.field)); ok {
+			d = geoDistance(it.lng, it.lat, lng, lat)
+		}
+		it.data = append(it.data, &pair{
+			key:   append([]byte{}, it.iter.Key()...),
+			value: v,
+		})
+		it.dist = append(it.dist, d)
+	}
+	sort.Stable(it)
+	return nil
+}
+
+func (it *nearIter) Len() int {
+	return len(it.data)
+}
+
+func (it *nearIter) Swap(i, j int) {
+	it.data[i], it.data[j] = it.data[j], it.data[i]
+	it.dist[i], it.dist[j] = it.dist[j], it.dist[i]
+}
+
+func (it *nearIter) Less(i, j int) bool {
+	return it.dist[i] < it.dist[j]
+}
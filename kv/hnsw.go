@@ -0,0 +1,562 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/q"
+)
+
+// HNSW (hierarchical navigable small world) index of vector field finds
+// approximate nearest vectors. Graph nodes are kept in bucket by document id,
+// node holds vector of document and its neighbours on each layer of graph.
+// Parameters of index and entry point of graph are kept in metadata bucket.
+
+const (
+	// number of neighbours of node on upper layers, layer 0 has twice more
+	hnswM = 16
+	// size of dynamic candidate list while building graph and searching
+	hnswEf = 64
+)
+
+type hnswMeta struct {
+	Metric q.VectorMetric `json:"metric"`
+	M      int            `json:"m"`
+	Ef     int            `json:"ef"`
+	Entry  string         `json:"entry"`
+	Level  int            `json:"level"`
+}
+
+type hnswNode struct {
+	Vector    []float32  `json:"v"`
+	Neighbors [][]string `json:"n"`
+}
+
+type hnsw struct {
+	hnswMeta
+	meta  Bucket
+	nodes Bucket
+	key   []byte
+	cache map[string]*hnswNode
+}
+
+// vecName returns name of bucket with HNSW graph of given collection field.
+func vecName(collection, field string) string {
+	return "vec_" + collection + "_" + field
+}
+
+func vecRegistryKey(collection, field string) []byte {
+	return []byte(collection + ".vec." + field)
+}
+
+// VectorIndex registers HNSW index of vector field of collection and builds it.
+// Vectors of any number of dimensions are indexed.
+func (s *store) VectorIndex(collection, field string, dims int, metric q.VectorMetric) error {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Bucket(collection, true)
+	if err != nil {
+		return err
+	}
+
+	err = s.migrate(tx, collection)
+	if err != nil {
+		return err
+	}
+
+	meta, err := tx.Bucket(metaBucket, true)
+	if err != nil {
+		return err
+	}
+
+	v, err := json.Marshal(hnswMeta{Metric: metric, M: hnswM, Ef: hnswEf})
+	if err != nil {
+		return err
+	}
+
+	err = meta.Set(vecRegistryKey(collection, field), v)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Bucket(vecName(collection, field), true)
+	if err != nil {
+		return err
+	}
+
+	err = buildVector(tx, collection, []string{field})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// vectorFields returns vector fields of collection with HNSW index.
+func vectorFields(tx Tx, collection string) ([]string, error) {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return nil, err
+	}
+
+	var prefix = vecRegistryKey(collection, "")
+	var fields []string
+	var c = meta.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		fields = append(fields, string(k[len(prefix):]))
+	}
+	return fields, nil
+}
+
+// buildVector rebuilds HNSW indexes of given fields.
+func buildVector(tx Tx, collection string, fields []string) error {
+	bucket, err := tx.Bucket(collection, false)
+	if bucket == nil || err != nil {
+		if err != nil {
+			return err
+		}
+		return errNotFound
+	}
+
+	for _, f := range fields {
+		h, err := openHNSW(tx, collection, f)
+		if h == nil || err != nil {
+			return err
+		}
+
+		err = clearBucket(h.nodes)
+		if err != nil {
+			return err
+		}
+		h.Entry = ""
+		h.Level = 0
+
+		var c = bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var data map[string]interface{}
+			err = unmarshal(v, &data)
+			if err != nil {
+				return err
+			}
+			if vec, ok := toVector(pathValue(data, f)); ok {
+				err = h.insert(keyID(k), vec)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err = h.save()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateVector maintains HNSW indexes of document, old is previous version of document.
+func (c *collectionIdx) updateVector(tx Tx, id string, data, old map[string]interface{}) error {
+	fields, err := vectorFields(tx, c.name)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		vec, ok := toVector(pathValue(data, f))
+		var prev, had = toVector(pathValue(old, f))
+		if ok == had && equalVectors(vec, prev) {
+			continue
+		}
+
+		h, err := openHNSW(tx, c.name, f)
+		if h == nil || err != nil {
+			return err
+		}
+		if had {
+			err = h.remove(id)
+			if err != nil {
+				return err
+			}
+		}
+		if ok {
+			err = h.insert(id, vec)
+			if err != nil {
+				return err
+			}
+		}
+		err = h.save()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanVector removes document from HNSW indexes.
+func (c *collectionIdx) cleanVector(tx Tx, id string, data map[string]interface{}) error {
+	return c.updateVector(tx, id, nil, data)
+}
+
+// openHNSW opens HNSW index of given field, returns nil if field is not indexed.
+func openHNSW(tx Tx, collection, field string) (*hnsw, error) {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return nil, err
+	}
+
+	var key = vecRegistryKey(collection, field)
+	v, err := meta.Get(key)
+	if v == nil || err != nil {
+		return nil, err
+	}
+
+	nodes, err := tx.Bucket(vecName(collection, field), false)
+	if nodes == nil || err != nil {
+		return nil, err
+	}
+
+	var h = &hnsw{
+		meta:  meta,
+		nodes: nodes,
+		key:   key,
+		cache: make(map[string]*hnswNode),
+	}
+	err = json.Unmarshal(v, &h.hnswMeta)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// save stores parameters and entry point of graph.
+func (h *hnsw) save() error {
+	v, err := json.Marshal(h.hnswMeta)
+	if err != nil {
+		return err
+	}
+	return h.meta.Set(h.key, v)
+}
+
+func (h *hnsw) node(id string) (*hnswNode, error) {
+	if n, ok := h.cache[id]; ok {
+		return n, nil
+	}
+	v, err := h.nodes.Get([]byte(id))
+	if v == nil || err != nil {
+		return nil, err
+	}
+	var n = &hnswNode{}
+	err = json.Unmarshal(v, n)
+	if err != nil {
+		return nil, err
+	}
+	h.cache[id] = n
+	return n, nil
+}
+
+func (h *hnsw) putNode(id string, n *hnswNode) error {
+	v, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	h.cache[id] = n
+	return h.nodes.Set([]byte(id), v)
+}
+
+func (h *hnsw) dist(a, b []float32) float64 {
+	return data.VectorDistance(h.Metric, a, b)
+}
+
+// maxNeighbors returns maximal number of neighbours of node on given layer.
+func (h *hnsw) maxNeighbors(level int) int {
+	if level == 0 {
+		return 2 * h.M
+	}
+	return h.M
+}
+
+// randomLevel returns top layer of new node with exponentially decaying probability.
+func (h *hnsw) randomLevel() int {
+	return int(-math.Log(1-rand.Float64()) / math.Log(float64(h.M)))
+}
+
+// search returns ids of k nodes nearest to given vector in order of distance.
+func (h *hnsw) search(vec []float32, k int) (keys, error) {
+	if len(h.Entry) == 0 {
+		return keys{}, nil
+	}
+	ep, err := h.node(h.Entry)
+	if ep == nil || err != nil {
+		return keys{}, err
+	}
+
+	var entries = []vectorCand{{h.Entry, h.dist(vec, ep.Vector)}}
+	for l := h.Level; l > 0; l-- {
+		entries, err = h.searchLayer(vec, entries, 1, l)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ef = h.Ef
+	if k > ef {
+		ef = k
+	}
+	found, err := h.searchLayer(vec, entries, ef, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) > k {
+		found = found[:k]
+	}
+	var ids = make(keys, len(found))
+	for i, c := range found {
+		ids[i] = c.id
+	}
+	return ids, nil
+}
+
+// searchLayer returns up to ef nodes of layer nearest to given vector in order of distance.
+func (h *hnsw) searchLayer(vec []float32, entries []vectorCand, ef, level int) ([]vectorCand, error) {
+	var visited = make(hashset)
+	var cands, found []vectorCand
+	for _, e := range entries {
+		visited.add(e.id)
+		cands = insertCand(cands, e)
+		found = insertCand(found, e)
+	}
+	if len(found) > ef {
+		found = found[:ef]
+	}
+
+	for len(cands) > 0 {
+		var c = cands[0]
+		cands = cands[1:]
+		if len(found) >= ef && c.d > found[len(found)-1].d {
+			break
+		}
+
+		n, err := h.node(c.id)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil || level >= len(n.Neighbors) {
+			continue
+		}
+
+		for _, id := range n.Neighbors[level] {
+			if !visited.add(id) {
+				continue
+			}
+			m, err := h.node(id)
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				// link to removed node
+				continue
+			}
+			var d = h.dist(vec, m.Vector)
+			if len(found) < ef || d < found[len(found)-1].d {
+				cands = insertCand(cands, vectorCand{id, d})
+				found = insertCand(found, vectorCand{id, d})
+				if len(found) > ef {
+					found = found[:ef]
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// insert adds node with given vector to graph.
+func (h *hnsw) insert(id string, vec []float32) error {
+	var level = h.randomLevel()
+	var n = &hnswNode{
+		Vector:    vec,
+		Neighbors: make([][]string, level+1),
+	}
+
+	ep, err := h.node(h.Entry)
+	if err != nil {
+		return err
+	}
+	if ep == nil {
+		h.Entry = id
+		h.Level = level
+		return h.putNode(id, n)
+	}
+
+	var entries = []vectorCand{{h.Entry, h.dist(vec, ep.Vector)}}
+	for l := h.Level; l > level; l-- {
+		entries, err = h.searchLayer(vec, entries, 1, l)
+		if err != nil {
+			return err
+		}
+	}
+
+	var top = level
+	if h.Level < top {
+		top = h.Level
+	}
+	for l := top; l >= 0; l-- {
+		entries, err = h.searchLayer(vec, entries, h.Ef, l)
+		if err != nil {
+			return err
+		}
+		for i, c := range entries {
+			if i >= h.M {
+				break
+			}
+			n.Neighbors[l] = append(n.Neighbors[l], c.id)
+		}
+		for _, nb := range n.Neighbors[l] {
+			m, err := h.node(nb)
+			if err != nil {
+				return err
+			}
+			if m == nil || l >= len(m.Neighbors) {
+				continue
+			}
+			m.Neighbors[l] = append(m.Neighbors[l], id)
+			m.Neighbors[l], err = h.prune(m, m.Neighbors[l], l, id, vec)
+			if err != nil {
+				return err
+			}
+			err = h.putNode(nb, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if level > h.Level {
+		h.Entry = id
+		h.Level = level
+	}
+	return h.putNode(id, n)
+}
+
+// prune keeps nearest neighbours of node on given layer.
+// Vector of node being inserted is given since the node is not stored yet.
+func (h *hnsw) prune(n *hnswNode, list []string, level int, newID string, newVec []float32) ([]string, error) {
+	var max = h.maxNeighbors(level)
+	if len(list) <= max {
+		return list, nil
+	}
+	var cands []vectorCand
+	for _, id := range list {
+		var vec = newVec
+		if id != newID {
+			m, err := h.node(id)
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				continue
+			}
+			vec = m.Vector
+		}
+		cands = insertCand(cands, vectorCand{id, h.dist(n.Vector, vec)})
+	}
+	if len(cands) > max {
+		cands = cands[:max]
+	}
+	var result = make([]string, len(cands))
+	for i, c := range cands {
+		result[i] = c.id
+	}
+	return result, nil
+}
+
+// remove deletes node from graph connecting its neighbours with each other.
+func (h *hnsw) remove(id string) error {
+	n, err := h.node(id)
+	if n == nil || err != nil {
+		return err
+	}
+
+	err = h.nodes.Delete([]byte(id))
+	if err != nil {
+		return err
+	}
+	delete(h.cache, id)
+
+	for l, list := range n.Neighbors {
+		for _, nb := range list {
+			m, err := h.node(nb)
+			if err != nil {
+				return err
+			}
+			if m == nil || l >= len(m.Neighbors) {
+				continue
+			}
+			var links = make(hashset)
+			var updated []string
+			for _, e := range m.Neighbors[l] {
+				if e != id && links.add(e) {
+					updated = append(updated, e)
+				}
+			}
+			for _, e := range list {
+				if e != nb && links.add(e) {
+					updated = append(updated, e)
+				}
+			}
+			m.Neighbors[l], err = h.prune(m, updated, l, "", nil)
+			if err != nil {
+				return err
+			}
+			err = h.putNode(nb, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if h.Entry == id {
+		return h.resetEntry()
+	}
+	return nil
+}
+
+// resetEntry makes node with the highest layer entry point of graph.
+func (h *hnsw) resetEntry() error {
+	h.Entry = ""
+	h.Level = 0
+	var c = h.nodes.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var n hnswNode
+		var err = json.Unmarshal(v, &n)
+		if err != nil {
+			return err
+		}
+		if len(h.Entry) == 0 || len(n.Neighbors)-1 > h.Level {
+			h.Entry = string(k)
+			h.Level = len(n.Neighbors) - 1
+		}
+	}
+	return nil
+}
+
+// insertCand inserts candidate to list ordered by distance.
+func insertCand(list []vectorCand, c vectorCand) []vectorCand {
+	var i = sort.Search(len(list), func(i int) bool {
+		return list[i].d > c.d || (list[i].d == c.d && strings.Compare(list[i].id, c.id) > 0)
+	})
+	list = append(list, vectorCand{})
+	copy(list[i+1:], list[i:])
+	list[i] = c
+	return list
+}
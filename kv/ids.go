@@ -0,0 +1,44 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// Documents are stored by keys preserving insertion order of sequence ids.
+// Decimal id (e.g. "10") is stored as zero byte followed by fixed-width big-endian number,
+// other ids are stored as is. Public id of document remains a string.
+
+const seqKeyLen = 9
+
+// idKey returns bucket key of given document id.
+func idKey(id string) []byte {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || strconv.FormatUint(n, 10) != id {
+		return []byte(id)
+	}
+	var k = make([]byte, seqKeyLen)
+	binary.BigEndian.PutUint64(k[1:], n)
+	return k
+}
+
+// keyID returns document id of given bucket key.
+func keyID(k []byte) string {
+	if isSeqKey(k) {
+		return strconv.FormatUint(binary.BigEndian.Uint64(k[1:]), 10)
+	}
+	return string(k)
+}
+
+func isSeqKey(k []byte) bool {
+	return len(k) == seqKeyLen && k[0] == 0
+}
+
+// sortIDs sorts given ids in order of their bucket keys.
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(idKey(ids[i]), idKey(ids[j])) < 0
+	})
+}
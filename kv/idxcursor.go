@@ -0,0 +1,106 @@
+package kv
+
+import (
+	"bytes"
+)
+
+// idxCursor iterates documents of collection in order of index entries.
+type idxCursor struct {
+	idx    Bucket
+	bucket Bucket
+	// optional set of accepted ids
+	ids    hashset
+	cursor Cursor
+}
+
+func (c *idxCursor) First() ([]byte, []byte) {
+	c.cursor = c.idx.Cursor()
+	k, _ := c.cursor.First()
+	return c.doc(k, c.cursor.Next)
+}
+
+func (c *idxCursor) Last() ([]byte, []byte) {
+	c.cursor = c.idx.Cursor()
+	k, _ := c.cursor.Last()
+	return c.doc(k, c.cursor.Prev)
+}
+
+func (c *idxCursor) Next() ([]byte, []byte) {
+	if c.cursor == nil {
+		return c.First()
+	}
+	k, _ := c.cursor.Next()
+	return c.doc(k, c.cursor.Next)
+}
+
+func (c *idxCursor) Prev() ([]byte, []byte) {
+	if c.cursor == nil {
+		return c.Last()
+	}
+	k, _ := c.cursor.Prev()
+	return c.doc(k, c.cursor.Prev)
+}
+
+// Seek is not supported, documents are ordered by index values.
+func (c *idxCursor) Seek(k []byte) ([]byte, []byte) {
+	return nil, nil
+}
+
+// doc returns first accepted document starting from given index entry.
+func (c *idxCursor) doc(k []byte, move func() ([]byte, []byte)) ([]byte, []byte) {
+	for ; k != nil; k, _ = move() {
+		var id = string(idxEntryID(k))
+		if c.ids != nil && !c.ids.has(id) {
+			continue
+		}
+		var key = idKey(id)
+		v, err := c.bucket.Get(key)
+		if err != nil {
+			debug.Err("bucket.Get", err)
+			return nil, nil
+		}
+		if v != nil {
+			return key, v
+		}
+	}
+	return nil, nil
+}
+
+// idxComplete determines whether index has entries of all documents of bucket,
+// documents without indexed values are sorted in memory (see idxValue).
+func idxComplete(tx Tx, bucket Bucket, name string) bool {
+	idx, err := tx.Bucket(name, false)
+	if idx == nil || err != nil {
+		return false
+	}
+	var n = 0
+	var c = bucket.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		n++
+	}
+	c = idx.Cursor()
+	for k, _ := c.First(); k != nil && n > 0; k, _ = c.Next() {
+		n--
+	}
+	return n == 0
+}
+
+// idxEntryID returns document id of index entry.
+func idxEntryID(k []byte) []byte {
+	return k[bytes.LastIndexByte(k, 0)+1:]
+}
+
+// reverseCursor iterates given cursor in descending order.
+type reverseCursor struct {
+	cursor Cursor
+}
+
+func (c *reverseCursor) First() ([]byte, []byte) { return c.cursor.Last() }
+func (c *reverseCursor) Last() ([]byte, []byte)  { return c.cursor.First() }
+func (c *reverseCursor) Next() ([]byte, []byte)  { return c.cursor.Prev() }
+func (c *reverseCursor) Prev() ([]byte, []byte)  { return c.cursor.Next() }
+
+// Seek is not supported in descending order.
+func (c *reverseCursor) Seek(k []byte) ([]byte, []byte) {
+	return nil, nil
+}
@@ -2,20 +2,23 @@ package kv
 
 import (
 	"bytes"
-	"fmt"
 	"reflect"
-	"sort"
 	"strings"
+	"time"
 
-	"github.com/gocontrib/nosql/reflection"
+	"github.com/gocontrib/nosql"
 )
 
-var stringType = reflect.TypeOf("")
+var (
+	stringType = reflect.TypeOf("")
+	timeType   = reflect.TypeOf(time.Time{})
+	pointType  = reflect.TypeOf(data.Point{})
+)
 
 type idxmeta struct {
 	name      string // name of index bucket
-	jsonField string
-	getter    reflection.Getter
+	jsonField string // dotted path of field in JSON document
+	time      bool   // whether field holds time values
 }
 
 type collectionIdx struct {
@@ -54,11 +57,23 @@ func (c *collectionIdx) makemeta(t reflect.Type) []idxmeta {
 		t = t.Elem()
 	}
 
-	var meta []idxmeta
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var meta = idxPaths(t, "")
+	for i := range meta {
+		meta[i].name = idxName(c.name, meta[i].jsonField)
+	}
+	return meta
+}
 
+// idxPaths returns dotted paths of indexed fields of given struct type including nested structs.
+func idxPaths(t reflect.Type, prefix string) []idxmeta {
+	var paths []idxmeta
 	for i := 0; i < t.NumField(); i++ {
 		var f = t.Field(i)
-		if f.Type != stringType {
+		if len(f.PkgPath) > 0 {
 			continue
 		}
 
@@ -66,172 +81,334 @@ func (c *collectionIdx) makemeta(t reflect.Type) []idxmeta {
 		var tag = f.Tag.Get("json")
 		if len(tag) > 0 {
 			var spec = strings.Split(tag, ",")
-			name = spec[0]
+			if len(spec[0]) > 0 {
+				name = spec[0]
+			}
 		}
-		if name == "id" || name == "_id" {
+		if name == "-" || (len(prefix) == 0 && (name == "id" || name == "_id")) {
 			continue
 		}
 
-		var idx = idxmeta{
-			name:      "idx_" + c.name + "_" + name,
-			jsonField: name,
-			getter:    reflection.MakeGetter(f),
+		var ft = f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
 		}
-		meta = append(meta, idx)
-	}
 
-	return meta
+		// time values are indexed by sortable keys (see idxValue),
+		// points have geospatial indexes (see GeoIndex)
+		switch {
+		case ft == pointType:
+		case ft == stringType || ft == timeType:
+			paths = append(paths, idxmeta{jsonField: prefix + name, time: ft == timeType})
+		case ft.Kind() == reflect.Struct:
+			paths = append(paths, idxPaths(ft, prefix+name+".")...)
+		}
+	}
+	return paths
 }
 
-func (c *collectionIdx) update(tx Tx, id string, doc interface{}, old map[string]interface{}) error {
+// update indexes of document, data is JSON representation of new document.
+func (c *collectionIdx) update(tx Tx, id string, doc interface{}, data, old map[string]interface{}) error {
 	for _, info := range c.getmeta(doc) {
-		var name = info.jsonField
-
-		var idx Bucket
-		var err error
-
-		// remove old index
-		if old != nil {
-			if idx == nil {
-				idx, err = tx.Bucket(info.name, true)
-				if err != nil {
-					return err
-				}
-			}
-
-			err = c.remove(idx, id, name, nil, old)
+		added, err := c.store.registerIdx(tx, c.name, info.jsonField, info.time)
+		if err != nil {
+			return err
+		}
+		if added {
+			// index of existing documents
+			err = buildIdx(tx, c.name, []string{info.jsonField})
 			if err != nil {
 				return err
 			}
 		}
+	}
 
-		var val = info.getter(doc).(string)
-		// insert new index
-		if len(val) > 0 {
+	fields, err := idxFields(tx, c.name)
+	if err != nil {
+		return err
+	}
+	var times = idxTimeFields(tx, c.name)
 
-			if idx == nil {
-				idx, err = tx.Bucket(info.name, true)
-				if err != nil {
-					return err
-				}
+	for _, name := range fields {
+		idx, err := tx.Bucket(idxName(c.name, name), true)
+		if err != nil {
+			return err
+		}
+
+		// remove old index, values of missing old document are nil
+		if val, ok := idxValue(pathValue(old, name), times.has(name)); ok {
+			err = idx.Delete(idxKey(val, id))
+			if err != nil {
+				return err
 			}
+		}
 
-			err = c.insert(idx, id, val)
+		// insert new index
+		if val, ok := idxValue(pathValue(data, name), times.has(name)); ok {
+			err = idx.Set(idxKey(val, id), []byte(id))
 			if err != nil {
 				return err
 			}
 		}
 	}
-	return nil
+	err = c.updateText(tx, id, data, old)
+	if err != nil {
+		return err
+	}
+	err = c.updateGeo(tx, id, data, old)
+	if err != nil {
+		return err
+	}
+	return c.updateVector(tx, id, data, old)
 }
 
-func (c *collectionIdx) insert(idx Bucket, id, value string) error {
-	var k = []byte(value)
-	v, err := idx.Get(k)
+func (c *collectionIdx) clean(tx Tx, id string, data map[string]interface{}) error {
+	fields, err := idxFields(tx, c.name)
 	if err != nil {
 		return err
 	}
-	if v != nil {
-		var keys = unmarshalKeys(v)
-		keys = append(keys, id)
-		return idx.Set(k, keys.marshal())
+	var times = idxTimeFields(tx, c.name)
+
+	for _, name := range fields {
+		idx, err := tx.Bucket(idxName(c.name, name), false)
+		if err != nil {
+			return err
+		}
+		if idx == nil {
+			continue
+		}
+
+		if val, ok := idxValue(pathValue(data, name), times.has(name)); ok {
+			err = idx.Delete(idxKey(val, id))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = c.cleanText(tx, id, data)
+	if err != nil {
+		return err
+	}
+	err = c.cleanGeo(tx, id, data)
+	if err != nil {
+		return err
 	}
-	return idx.Set(k, keys{id}.marshal())
+	return c.cleanVector(tx, id, data)
 }
 
-func (c *collectionIdx) remove(idx Bucket, id, name string, value interface{}, old map[string]interface{}) error {
-	if value == nil {
-		v, ok := old[name]
-		if !ok {
-			return nil
+// Index bucket holds one entry per (value, id) pair. Key of entry is value
+// and id separated by zero byte, so all ids of given value are
+// adjacent and can be found with prefix scan. Only string and time values
+// are indexed, so documents with missing or other values have no entries
+// and index is walked to sort documents only if it is complete (see idxComplete).
+
+// idxName returns name of index bucket for given collection field.
+func idxName(collection, field string) string {
+	return "idx_" + collection + "_" + field
+}
+
+// idxKey makes key of index entry.
+func idxKey(value, id string) []byte {
+	var k = make([]byte, 0, len(value)+len(id)+1)
+	k = append(k, value...)
+	k = append(k, 0)
+	return append(k, id...)
+}
+
+// idxTimeLayout is layout of index keys of time values, they are fixed-width UTC times
+// (RFC 3339 with nine fraction digits), so byte order of keys is time order.
+const idxTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// idxValue returns indexed value of document field, JSON strings of time fields are
+// indexed as sortable keys of times. It returns false if value is not indexed.
+func idxValue(v interface{}, isTime bool) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		if isTime {
+			if tm, err := time.Parse(time.RFC3339Nano, t); err == nil {
+				return tm.UTC().Format(idxTimeLayout), true
+			}
 		}
-		value = v
+		return t, true
+	case time.Time:
+		return t.UTC().Format(idxTimeLayout), true
+	}
+	return "", false
+}
+
+// idxScan returns ids of documents indexed with given value.
+func idxScan(idx Bucket, value string) keys {
+	var prefix = idxKey(value, "")
+	var list keys
+	var c = idx.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		list = append(list, string(k[len(prefix):]))
 	}
+	return list
+}
 
-	s, ok := value.(string)
-	if !ok {
-		return debug.Err(fmt.Sprintf("idx.remove of %T", value), errNotString)
+// idxPrefixScan returns ids of documents indexed with values starting with given prefix.
+func idxPrefixScan(idx Bucket, prefix string) keys {
+	var list keys
+	var c = idx.Cursor()
+	for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+		list = append(list, string(idxEntryID(k)))
 	}
+	return list
+}
+
+// idxTx is write transaction of store, indexes registered in transaction
+// are cached by store only after successful commit (see registerIdx).
+type idxTx struct {
+	Tx
+	store      *store
+	registered []string
+}
 
-	var k = []byte(s)
-	val, err := idx.Get(k)
-	if val == nil || err != nil {
+func (t *idxTx) Commit() error {
+	var err = t.Tx.Commit()
+	if err != nil {
 		return err
 	}
+	var s = t.store
+	s.Lock()
+	if s.registered == nil {
+		s.registered = make(map[string]bool)
+	}
+	for _, name := range t.registered {
+		s.registered[name] = true
+	}
+	s.Unlock()
+	return nil
+}
 
-	var keys = unmarshalKeys(val)
-	keys = keys.remove(id)
+// begin starts write transaction which can register indexes.
+func (s *store) begin() (Tx, error) {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return &idxTx{Tx: tx, store: s}, nil
+}
 
-	if len(keys) == 0 {
-		return idx.Delete(k)
+// registerIdx records indexed field of collection in metadata bucket.
+// Returns true if field was not registered before.
+func (s *store) registerIdx(tx Tx, collection, field string, isTime bool) (bool, error) {
+	var name = idxName(collection, field)
+	if isTime {
+		name += ":time"
 	}
 
-	return idx.Set(k, keys.marshal())
-}
+	s.Lock()
+	var done = s.registered[name]
+	s.Unlock()
+	if done {
+		return false, nil
+	}
 
-func (c *collectionIdx) clean(tx Tx, id string, data map[string]interface{}) error {
-	for name, value := range data {
-		var _, ok = value.(string)
-		if !ok {
-			continue
-		}
+	meta, err := tx.Bucket(metaBucket, true)
+	if err != nil {
+		return false, err
+	}
+
+	var k = idxRegistryKey(collection, field)
+	v, err := meta.Get(k)
+	if err != nil {
+		return false, err
+	}
 
-		var idxName = "idx_" + c.name + "_" + name
-		idx, err := tx.Bucket(idxName, false)
+	var added = v == nil
+	if added {
+		err = meta.Set(k, []byte(field))
 		if err != nil {
-			return err
-		}
-		if idx == nil {
-			continue
+			return false, err
 		}
+	}
 
-		err = c.remove(idx, id, name, value, data)
+	if isTime {
+		// index of time field registered before is rebuilt with keys of times
+		k = idxTimeRegistryKey(collection, field)
+		v, err = meta.Get(k)
 		if err != nil {
-			return err
+			return false, err
+		}
+		if v == nil {
+			added = true
+			err = meta.Set(k, []byte(field))
+			if err != nil {
+				return false, err
+			}
 		}
 	}
-	return nil
-}
 
-type keys []string
+	// rolled back registration must not be cached
+	if t, ok := tx.(*idxTx); ok {
+		t.registered = append(t.registered, name)
+	}
 
-var emptyKeys = keys{}
+	return added, nil
+}
 
-func (a keys) marshal() []byte {
-	sort.Strings(a)
-	var buf = bytes.NewBuffer([]byte{})
-	for _, k := range a {
-		buf.Write([]byte(k))
-		buf.WriteByte(0)
+// idxFields returns registered indexed fields of collection.
+func idxFields(tx Tx, collection string) ([]string, error) {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return nil, err
 	}
-	return buf.Bytes()
+
+	var prefix = idxRegistryKey(collection, "")
+	var fields []string
+	var c = meta.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		fields = append(fields, string(v))
+	}
+	return fields, nil
 }
 
-func unmarshalKeys(b []byte) keys {
-	var list keys
-	var start = 0
-	for i := 0; i < len(b); i++ {
-		if b[i] == 0 {
-			list = append(list, string(b[start:i]))
-			start = i + 1
-		}
+// hasIdx determines whether given field of collection is indexed.
+func hasIdx(tx Tx, collection, field string) bool {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return false
 	}
-	return list
+	v, err := meta.Get(idxRegistryKey(collection, field))
+	return v != nil && err == nil
 }
 
-func (a keys) remove(s string) keys {
-	var i = indexOfString(a, s)
-	if i >= 0 {
-		return append(a[:i], a[i+1:]...)
+func idxRegistryKey(collection, field string) []byte {
+	return []byte(collection + ".idx." + field)
+}
+
+// idxTimeFields returns registered indexed fields of collection holding time values.
+func idxTimeFields(tx Tx, collection string) hashset {
+	var fields = make(hashset)
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return fields
+	}
+
+	var prefix = idxTimeRegistryKey(collection, "")
+	var c = meta.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		fields.add(string(v))
 	}
-	return a
+	return fields
 }
 
-func indexOfString(a []string, s string) int {
-	for i, v := range a {
-		if v == s {
-			return i
-		}
+// hasTimeIdx determines whether given indexed field of collection holds time values.
+func hasTimeIdx(tx Tx, collection, field string) bool {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return false
 	}
-	return -1
+	v, err := meta.Get(idxTimeRegistryKey(collection, field))
+	return v != nil && err == nil
+}
+
+func idxTimeRegistryKey(collection, field string) []byte {
+	return []byte(collection + ".idxtime." + field)
 }
+
+type keys []string
+
+var emptyKeys = keys{}
@@ -1,9 +1,12 @@
 package kv
 
 // Cursor defines interface of cursor in KV store.
+// Keys are iterated in ascending byte order.
 type Cursor interface {
 	First() ([]byte, []byte)
+	Last() ([]byte, []byte)
 	Next() ([]byte, []byte)
+	Prev() ([]byte, []byte)
 	Seek(k []byte) ([]byte, []byte)
 }
 
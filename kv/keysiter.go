@@ -1,24 +1,21 @@
 package kv
 
+import "bytes"
+
 // KeysIter makes iterator  over specified keys.
-func KeysIter(it Cursor, keys []string, limit, skip int64) Iter {
+func KeysIter(bucket Bucket, keys []string) Iter {
 	return &keysIter{
-		cursor: it,
+		bucket: bucket,
 		keys:   keys,
-		limit:  limit,
-		skip:   skip,
+		idx:    -1,
 	}
 }
 
 type keysIter struct {
-	cursor      Cursor
-	keys        []string
-	limit       int64
-	skip        int64
-	idx         int
-	count       int64
-	initialized bool
-	closed      bool
+	bucket Bucket
+	keys   []string
+	idx    int
+	closed bool
 	// current pair
 	k []byte
 	v []byte
@@ -37,46 +34,21 @@ func (it *keysIter) Next() (bool, error) {
 		return false, nil
 	}
 
-	// support limit
-	if it.limit > 0 && it.count >= it.limit {
-		it.close()
-		return false, nil
-	}
-
-	if !it.initialized {
-		it.initialized = true
-		var skip = it.skip
-		for skip > 0 && it.idx < len(it.keys) {
-			// check key
-			k := []byte(it.keys[it.idx])
-			it.idx++
-			k, _ = it.cursor.Seek(k)
-			if k != nil {
-				skip--
-			}
+	for it.idx++; it.idx < len(it.keys); it.idx++ {
+		k := idKey(it.keys[it.idx])
+		v, err := it.bucket.Get(k)
+		if err != nil {
+			return false, err
 		}
-	} else {
-		it.idx++
-	}
-
-	for it.idx < len(it.keys) {
-		k := []byte(it.keys[it.idx])
-		k, v := it.cursor.Seek(k)
-		if k != nil {
+		if v != nil {
 			it.k = k
 			it.v = v
-			it.count++
-			break
+			return true, nil
 		}
-		it.idx++
 	}
 
-	if it.idx >= len(it.keys) {
-		it.close()
-		return false, nil
-	}
-
-	return true, nil
+	it.close()
+	return false, nil
 }
 
 func (it *keysIter) close() {
@@ -86,3 +58,57 @@ func (it *keysIter) close() {
 		it.v = nil
 	}
 }
+
+// keysCursor iterates documents with given ids in given order.
+type keysCursor struct {
+	bucket Bucket
+	keys   keys
+	pos    int
+}
+
+func (c *keysCursor) First() ([]byte, []byte) {
+	c.pos = -1
+	return c.Next()
+}
+
+func (c *keysCursor) Last() ([]byte, []byte) {
+	c.pos = len(c.keys)
+	return c.Prev()
+}
+
+func (c *keysCursor) Next() ([]byte, []byte) {
+	for c.pos++; c.pos < len(c.keys); c.pos++ {
+		if k, v := c.get(); k != nil {
+			return k, v
+		}
+	}
+	return nil, nil
+}
+
+func (c *keysCursor) Prev() ([]byte, []byte) {
+	for c.pos--; c.pos >= 0; c.pos-- {
+		if k, v := c.get(); k != nil {
+			return k, v
+		}
+	}
+	return nil, nil
+}
+
+func (c *keysCursor) Seek(k []byte) ([]byte, []byte) {
+	// keys are not ordered, seek finds exact key only
+	for c.pos = 0; c.pos < len(c.keys); c.pos++ {
+		if bytes.Equal(idKey(c.keys[c.pos]), k) {
+			return c.get()
+		}
+	}
+	return nil, nil
+}
+
+func (c *keysCursor) get() ([]byte, []byte) {
+	var k = idKey(c.keys[c.pos])
+	v, err := c.bucket.Get(k)
+	if v == nil || err != nil {
+		return nil, nil
+	}
+	return k, v
+}
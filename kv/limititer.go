@@ -0,0 +1,69 @@
+package kv
+
+import "io"
+
+// LimitIter creates iterator which ignores first skip results
+// and stops after limit results.
+func LimitIter(iter Iter, skip, limit int64) Iter {
+	if skip <= 0 && limit <= 0 {
+		return iter
+	}
+	return &limitIter{
+		iter:  iter,
+		skip:  skip,
+		limit: limit,
+	}
+}
+
+type limitIter struct {
+	iter   Iter
+	skip   int64
+	limit  int64
+	count  int64
+	closed bool
+}
+
+func (it *limitIter) Key() []byte {
+	return it.iter.Key()
+}
+
+func (it *limitIter) Value() []byte {
+	return it.iter.Value()
+}
+
+func (it *limitIter) Next() (bool, error) {
+	if it.closed {
+		return false, nil
+	}
+
+	// support limit
+	if it.limit > 0 && it.count >= it.limit {
+		it.closed = true
+		return false, nil
+	}
+
+	for ; it.skip > 0; it.skip-- {
+		ok, err := it.iter.Next()
+		if err != nil || !ok {
+			it.closed = true
+			return false, err
+		}
+	}
+
+	ok, err := it.iter.Next()
+	if err != nil || !ok {
+		it.closed = true
+		return false, err
+	}
+
+	it.count++
+	return true, nil
+}
+
+func (it *limitIter) Close() error {
+	it.closed = true
+	if c, ok := it.iter.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -6,9 +6,23 @@ import (
 	"github.com/gocontrib/nosql/q"
 )
 
+// idsMatch is resolved condition (e.g. text search), it holds ids of matching documents.
+type idsMatch struct {
+	ids hashset
+}
+
 type lookup struct {
 	collection *collection
 	tx         Tx
+	// names of used indexes if they are explained
+	indexes hashset
+}
+
+// use records name of used index.
+func (c lookup) use(name string) {
+	if c.indexes != nil {
+		c.indexes.add(name)
+	}
 }
 
 func (c lookup) find(f []interface{}) keys {
@@ -23,6 +37,8 @@ func (c lookup) condition(f interface{}) keys {
 		return c.and(t)
 	case q.Or:
 		return c.or(t)
+	case idsMatch:
+		return t.ids.toArray()
 	case q.M:
 		var set hashset
 		for name, val := range t {
@@ -59,24 +75,59 @@ func (c lookup) field(name string, value interface{}) keys {
 		return keys{s}
 	}
 
-	var idxName = "idx_" + c.collection.name + "_" + name
-	idx, err := c.tx.Bucket(idxName, false)
+	switch value.(type) {
+	case q.GeoNear, q.GeoWithin:
+		return c.geo(name, value)
+	}
+
+	idx, err := c.tx.Bucket(idxName(c.collection.name, name), false)
 	if err != nil || idx == nil {
 		return emptyKeys
 	}
+	c.use(idxName(c.collection.name, name))
 
 	switch v := value.(type) {
 	case string:
-		raw, err := idx.Get([]byte(v))
-		if err != nil || raw == nil {
-			return emptyKeys
+		return idxScan(idx, v)
+	case q.Op:
+		if s, ok := v.Value.(string); ok && v.Kind == q.OpPrefix {
+			return idxPrefixScan(idx, s)
 		}
-		return unmarshalKeys(raw)
 	}
 
 	return emptyKeys
 }
 
+// geo finds documents satisfying geospatial condition on point field
+// checking documents in cells of geospatial index covering area of condition.
+func (c lookup) geo(name string, cond interface{}) keys {
+	idx, err := c.tx.Bucket(geoName(c.collection.name, name), false)
+	if err != nil || idx == nil {
+		return emptyKeys
+	}
+	c.use(geoName(c.collection.name, name))
+	bucket, err := c.tx.Bucket(c.collection.name, false)
+	if err != nil || bucket == nil {
+		return emptyKeys
+	}
+	var p = geoFilter(cond)
+	var list keys
+	for _, id := range geoScan(idx, geoArea(cond)) {
+		v, err := bucket.Get(idKey(id))
+		if err != nil || v == nil {
+			continue
+		}
+		var data map[string]interface{}
+		if unmarshal(v, &data) != nil {
+			continue
+		}
+		if p(pathValue(data, name)) {
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
 func (c lookup) and(f []interface{}) keys {
 	if len(f) == 1 {
 		return c.condition(f[0])
@@ -120,6 +171,8 @@ func (c lookup) isSuitable(f interface{}) bool {
 	switch t := f.(type) {
 	case q.Not:
 		return false
+	case idsMatch:
+		return true
 	case q.And:
 		for _, i := range t {
 			if !c.isSuitable(i) {
@@ -144,24 +197,34 @@ func (c lookup) isSuitable(f interface{}) bool {
 	case q.M:
 		for name, v := range t {
 			switch v.(type) {
+			case q.GeoNear, q.GeoWithin:
+				if !hasGeoIdx(c.tx, c.collection.name, name) {
+					return false
+				}
+				continue
+			}
+			switch op := v.(type) {
 			case q.In:
 				return false
 			case q.NotIn:
 				return false
 			case q.Op:
-				return false
+				// prefix of indexed string
+				if op.Kind != q.OpPrefix || name == "id" || name == "_id" {
+					return false
+				}
+				v = op.Value
 			}
 			// now only strings are indexed
 			s, ok := v.(string)
-			if !ok {
-				return len(s) > 0
+			if !ok || len(s) == 0 {
+				return false
 			}
 			if name == "id" || name == "_id" {
-				return true
+				continue
 			}
-			var idxName = "idx_" + c.collection.name + "_" + name
-			idx, err := c.tx.Bucket(idxName, false)
-			if err != nil || idx == nil {
+			// keys of time fields are not JSON strings (see idxValue)
+			if !hasIdx(c.tx, c.collection.name, name) || hasTimeIdx(c.tx, c.collection.name, name) {
 				return false
 			}
 		}
@@ -0,0 +1,160 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+const (
+	// name of bucket with store metadata
+	metaBucket = "_meta"
+	// current version of index format
+	idxFormat = "5"
+	// current version of document keys format
+	keyFormat = "2"
+)
+
+// migrate upgrades storage format of given collection if needed.
+func (s *store) migrate(tx Tx, name string) error {
+	s.Lock()
+	var done = s.migrated[name]
+	s.Unlock()
+	if done {
+		return nil
+	}
+
+	meta, err := tx.Bucket(metaBucket, true)
+	if err != nil {
+		return err
+	}
+
+	var kk = []byte(name + ".key_format")
+	v, err := meta.Get(kk)
+	if err != nil {
+		return err
+	}
+
+	if string(v) != keyFormat {
+		err = migrateKeys(tx, name)
+		if err != nil {
+			return debug.Err("migrate keys", err)
+		}
+		err = meta.Set(kk, []byte(keyFormat))
+		if err != nil {
+			return err
+		}
+	}
+
+	var k = []byte(name + ".idx_format")
+	v, err = meta.Get(k)
+	if err != nil {
+		return err
+	}
+
+	if string(v) != idxFormat {
+		err = s.migrateIdx(tx, name)
+		if err != nil {
+			return debug.Err("migrate indexes", err)
+		}
+		err = meta.Set(k, []byte(idxFormat))
+		if err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
+	if s.migrated == nil {
+		s.migrated = make(map[string]bool)
+	}
+	s.migrated[name] = true
+	s.Unlock()
+
+	return nil
+}
+
+// migrateIdx registers existing indexes of given collection and rebuilds them.
+// Legacy index entries (one entry per value holding zero separated list of ids)
+// are replaced with one entry per (value, id) pair.
+func (s *store) migrateIdx(tx Tx, name string) error {
+	bucket, err := tx.Bucket(name, false)
+	if bucket == nil || err != nil {
+		return err
+	}
+
+	// only string fields are indexed
+	var fields = make(map[string]bool)
+	var c = bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var data map[string]interface{}
+		err = json.Unmarshal(v, &data)
+		if err != nil {
+			return err
+		}
+		for f, val := range data {
+			if _, ok := val.(string); ok {
+				fields[f] = true
+			}
+		}
+	}
+
+	for f := range fields {
+		if f == "id" || f == "_id" {
+			continue
+		}
+		idx, err := tx.Bucket(idxName(name, f), false)
+		if err != nil {
+			return err
+		}
+		if idx == nil {
+			continue
+		}
+		if k, _ := idx.Cursor().First(); k == nil {
+			continue
+		}
+		_, err = s.registerIdx(tx, name, f, hasTimeIdx(tx, name, f))
+		if err != nil {
+			return err
+		}
+	}
+
+	registered, err := idxFields(tx, name)
+	if err != nil {
+		return err
+	}
+
+	return buildIdx(tx, name, registered)
+}
+
+// migrateKeys replaces legacy decimal keys of documents (e.g. "10")
+// with order-preserving keys of sequence ids.
+func migrateKeys(tx Tx, name string) error {
+	bucket, err := tx.Bucket(name, false)
+	if bucket == nil || err != nil {
+		return err
+	}
+
+	var list [][]byte
+	var c = bucket.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		if !bytes.Equal(idKey(string(k)), k) {
+			list = append(list, append([]byte{}, k...))
+		}
+	}
+
+	for _, k := range list {
+		v, err := bucket.Get(k)
+		if err != nil {
+			return err
+		}
+		err = bucket.Delete(k)
+		if err != nil {
+			return err
+		}
+		err = bucket.Set(idKey(string(k)), append([]byte{}, v...))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package kv
+
+import "strings"
+
+// lookupPath returns value of document field with given dotted path (e.g. "address.city").
+func lookupPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := doc[path]; ok {
+		return v, true
+	}
+	var i = strings.IndexByte(path, '.')
+	if i < 0 {
+		return nil, false
+	}
+	m, ok := doc[path[:i]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookupPath(m, path[i+1:])
+}
+
+// pathValue returns value of document field with given dotted path or nil if it is missing.
+func pathValue(doc map[string]interface{}, path string) interface{} {
+	v, _ := lookupPath(doc, path)
+	return v
+}
@@ -0,0 +1,269 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/gocontrib/nosql"
+)
+
+// IndexReport describes state of secondary indexes of collection.
+type IndexReport struct {
+	Collection string
+	Indexes    []IndexStatus
+}
+
+// IndexStatus describes state of index of one field.
+type IndexStatus struct {
+	Field string
+	// Entries is number of entries in index bucket.
+	Entries int64
+	// Orphaned entries point to missing documents or to stale values.
+	Orphaned []IndexEntry
+	// Missing entries exist in documents but not in index bucket.
+	Missing []IndexEntry
+}
+
+// IndexEntry is (value, id) pair of index.
+type IndexEntry struct {
+	ID    string
+	Value string
+}
+
+// OK determines whether all indexes are consistent with documents.
+func (r *IndexReport) OK() bool {
+	for _, i := range r.Indexes {
+		if len(i.Orphaned) > 0 || len(i.Missing) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Reindex rebuilds secondary indexes of given collection from its documents.
+// Given fields are registered as indexed, by default all registered indexes
+// including full-text, geospatial and vector indexes are rebuilt.
+func Reindex(ds data.Store, collection string, fields ...string) error {
+	s, ok := ds.(*store)
+	if !ok {
+		return errNotKVStore
+	}
+
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for _, f := range fields {
+		// time fields keep keys of times (see idxValue)
+		_, err = s.registerIdx(tx, collection, f, hasTimeIdx(tx, collection, f))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(fields) == 0 {
+		fields, err = idxFields(tx, collection)
+		if err != nil {
+			return err
+		}
+		text, err := textFields(tx, collection)
+		if err != nil {
+			return err
+		}
+		if len(text) > 0 {
+			err = buildText(tx, collection, text)
+			if err != nil {
+				return err
+			}
+		}
+		geo, err := geoFields(tx, collection)
+		if err != nil {
+			return err
+		}
+		err = buildGeo(tx, collection, geo)
+		if err != nil {
+			return err
+		}
+		vec, err := vectorFields(tx, collection)
+		if err != nil {
+			return err
+		}
+		err = buildVector(tx, collection, vec)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = buildIdx(tx, collection, fields)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// buildIdx rebuilds indexes of given fields.
+func buildIdx(tx Tx, collection string, fields []string) error {
+	bucket, err := tx.Bucket(collection, false)
+	if bucket == nil || err != nil {
+		if err != nil {
+			return err
+		}
+		return errNotFound
+	}
+
+	values, err := fieldValues(bucket, fields, idxTimeFields(tx, collection))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		idx, err := tx.Bucket(idxName(collection, f), true)
+		if err != nil {
+			return err
+		}
+
+		err = clearBucket(idx)
+		if err != nil {
+			return err
+		}
+
+		for id, val := range values[f] {
+			err = idx.Set(idxKey(val, id), []byte(id))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// VerifyIndexes compares secondary indexes of given collection with its documents.
+func VerifyIndexes(ds data.Store, collection string) (*IndexReport, error) {
+	s, ok := ds.(*store)
+	if !ok {
+		return nil, errNotKVStore
+	}
+
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	bucket, err := tx.Bucket(collection, false)
+	if bucket == nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errNotFound
+	}
+
+	fields, err := idxFields(tx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := fieldValues(bucket, fields, idxTimeFields(tx, collection))
+	if err != nil {
+		return nil, err
+	}
+
+	var report = &IndexReport{Collection: collection}
+
+	for _, f := range fields {
+		var status = IndexStatus{Field: f}
+		var values = all[f]
+
+		idx, err := tx.Bucket(idxName(collection, f), false)
+		if err != nil {
+			return nil, err
+		}
+
+		var found = make(hashset)
+		if idx != nil {
+			var c = idx.Cursor()
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+				status.Entries++
+				var i = bytes.LastIndexByte(k, 0)
+				if i < 0 {
+					status.Orphaned = append(status.Orphaned, IndexEntry{Value: string(k)})
+					continue
+				}
+				var e = IndexEntry{
+					ID:    string(k[i+1:]),
+					Value: string(k[:i]),
+				}
+				if val, ok := values[e.ID]; !ok || val != e.Value {
+					status.Orphaned = append(status.Orphaned, e)
+					continue
+				}
+				found.add(e.ID)
+			}
+		}
+
+		for _, id := range newHashset(keysOf(values)).toArray() {
+			if !found.has(id) {
+				status.Missing = append(status.Missing, IndexEntry{
+					ID:    id,
+					Value: values[id],
+				})
+			}
+		}
+
+		report.Indexes = append(report.Indexes, status)
+	}
+
+	return report, nil
+}
+
+// fieldValues returns indexed values of given fields by document id,
+// documents without indexed values are omitted (see idxValue).
+func fieldValues(bucket Bucket, fields []string, times hashset) (map[string]map[string]string, error) {
+	var values = make(map[string]map[string]string)
+	for _, f := range fields {
+		values[f] = make(map[string]string)
+	}
+	var c = bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var data map[string]interface{}
+		var err = json.Unmarshal(v, &data)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			if val, ok := idxValue(pathValue(data, f), times.has(f)); ok {
+				values[f][keyID(k)] = val
+			}
+		}
+	}
+	return values, nil
+}
+
+func clearBucket(b Bucket) error {
+	var list [][]byte
+	var c = b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		list = append(list, append([]byte{}, k...))
+	}
+	for _, k := range list {
+		var err = b.Delete(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func keysOf(m map[string]string) []string {
+	var a []string
+	for k := range m {
+		a = append(a, k)
+	}
+	return a
+}
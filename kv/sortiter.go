@@ -1,45 +1,75 @@
 package kv
 
 import (
+	"container/heap"
 	"encoding/json"
+	"io"
 	"sort"
 	"strings"
 
 	"github.com/gocontrib/nosql/util"
 )
 
+// SortMemoryLimit is approximate amount of memory in bytes
+// which sort can use before it spills sorted runs to temporary files.
+var SortMemoryLimit int64 = 64 << 20
+
+// estimated memory overhead of one buffered item
+const pairOverhead = 96
+
 // SortIter creates sortable iterator.
-func SortIter(iter Iter, sort []string) Iter {
+// If top is positive only first top results are kept using bounded heap.
+func SortIter(iter Iter, sort []string, top int64) Iter {
 	if len(sort) == 0 {
 		return iter
 	}
+	var fields []string
+	var desc []bool
+	for _, k := range sort {
+		if strings.HasPrefix(k, "-") {
+			fields = append(fields, k[1:])
+			desc = append(desc, true)
+		} else {
+			fields = append(fields, k)
+			desc = append(desc, false)
+		}
+	}
 	return &sortIter{
-		iter: iter,
-		sort: sort,
+		iter:   iter,
+		fields: fields,
+		desc:   desc,
+		top:    top,
 	}
 }
 
 type pair struct {
 	key   []byte
 	value []byte
-	data  map[string]interface{}
+	vals  []interface{} // values of sort fields
+	seq   uint64
 }
 
 type sortIter struct {
 	iter        Iter
-	sort        []string
+	fields      []string
+	desc        []bool
+	top         int64
 	initialized bool
 	closed      bool
 	data        []*pair
+	mem         int64
+	runs        []*fileRun
+	merge       *mergeHeap
 	idx         int
+	cur         *pair
 }
 
 func (c *sortIter) Key() []byte {
-	return c.data[c.idx].key
+	return c.cur.key
 }
 
 func (c *sortIter) Value() []byte {
-	return c.data[c.idx].value
+	return c.cur.value
 }
 
 func (c *sortIter) Next() (bool, error) {
@@ -48,36 +78,160 @@ func (c *sortIter) Next() (bool, error) {
 	}
 	if !c.initialized {
 		c.initialized = true
-		for {
-			ok, err := c.iter.Next()
-			if err != nil {
-				return false, err
-			}
-			if !ok {
-				break
-			}
-			var p = &pair{
-				key:   c.iter.Key(),
-				value: c.iter.Value(),
-				data:  make(map[string]interface{}),
-			}
-			err = json.Unmarshal(p.value, &p.data)
-			if err != nil {
-				panic(err)
-			}
-			c.data = append(c.data, p)
-			sort.Sort(c)
+		var err = c.load()
+		if err != nil {
+			c.Close()
+			return false, err
+		}
+	}
+
+	var p *pair
+	if c.merge != nil {
+		var err error
+		p, err = c.merge.next()
+		if err != nil {
+			c.Close()
+			return false, err
 		}
-	} else {
+	} else if c.idx < len(c.data) {
+		p = c.data[c.idx]
 		c.idx++
 	}
-	if c.idx >= len(c.data) {
-		c.closed = true
+
+	if p == nil {
+		c.Close()
 		return false, nil
 	}
+
+	c.cur = p
 	return true, nil
 }
 
+// Close releases buffered data and removes temporary files.
+func (c *sortIter) Close() error {
+	c.closed = true
+	c.data = nil
+	c.merge = nil
+	var err error
+	for _, r := range c.runs {
+		if e := r.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	c.runs = nil
+	if cl, ok := c.iter.(io.Closer); ok {
+		if e := cl.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (c *sortIter) load() error {
+	for seq := uint64(0); ; seq++ {
+		ok, err := c.iter.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		var p = &pair{
+			key:   c.iter.Key(),
+			value: c.iter.Value(),
+			seq:   seq,
+		}
+		err = c.decode(p)
+		if err != nil {
+			return err
+		}
+		c.add(p)
+		if c.mem > SortMemoryLimit {
+			err = c.spill()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	sort.Sort(c)
+
+	if len(c.runs) == 0 {
+		return nil
+	}
+
+	// k-way merge of spilled runs and rest of data
+	var h = &mergeHeap{sort: c}
+	for _, r := range c.runs {
+		err := h.add(r)
+		if err != nil {
+			return err
+		}
+	}
+	err := h.add(&memRun{data: c.limit(c.data)})
+	if err != nil {
+		return err
+	}
+	c.data = nil
+	c.merge = h
+	return nil
+}
+
+func (c *sortIter) add(p *pair) {
+	var size = int64(len(p.key)+len(p.value)) + pairOverhead
+	if c.top <= 0 {
+		c.data = append(c.data, p)
+		c.mem += size
+		return
+	}
+	// keep top results in max-heap where root is the last one
+	if int64(len(c.data)) < c.top {
+		heap.Push((*topHeap)(c), p)
+		c.mem += size
+		return
+	}
+	if c.less(p, c.data[0]) {
+		c.mem += size - int64(len(c.data[0].key)+len(c.data[0].value)) - pairOverhead
+		c.data[0] = p
+		heap.Fix((*topHeap)(c), 0)
+	}
+}
+
+// spill writes buffered data as sorted run to temporary file.
+func (c *sortIter) spill() error {
+	sort.Sort(c)
+	r, err := writeRun(c.limit(c.data))
+	if err != nil {
+		return debug.Err("sort spill", err)
+	}
+	c.runs = append(c.runs, r)
+	c.data = nil
+	c.mem = 0
+	return nil
+}
+
+// limit returns first top items of sorted data, only they can be in result.
+func (c *sortIter) limit(data []*pair) []*pair {
+	if c.top > 0 && int64(len(data)) > c.top {
+		return data[:c.top]
+	}
+	return data
+}
+
+// decode extracts values of sort fields from document.
+func (c *sortIter) decode(p *pair) error {
+	var data map[string]interface{}
+	var err = json.Unmarshal(p.value, &data)
+	if err != nil {
+		return debug.Err("json.Unmarshal", err)
+	}
+	p.vals = make([]interface{}, len(c.fields))
+	for i, k := range c.fields {
+		p.vals[i] = pathValue(data, k)
+	}
+	return nil
+}
+
 // sort.Interface
 func (c *sortIter) Len() int {
 	return len(c.data)
@@ -88,30 +242,46 @@ func (c *sortIter) Swap(i, j int) {
 }
 
 func (c *sortIter) Less(i, j int) bool {
-	var a = c.data[i]
-	var b = c.data[j]
-	for _, k := range c.sort {
-		var asc = true
-		if strings.HasPrefix(k, "-") {
-			k = k[1:]
-			asc = false
-		}
-		var v1, ok1 = a.data[k]
-		if !ok1 {
-			v1 = nil
-		}
-		var v2, ok2 = b.data[k]
-		if !ok2 {
-			v2 = nil
-		}
-		var t = util.Compare(v1, v2)
+	return c.less(c.data[i], c.data[j])
+}
+
+func (c *sortIter) less(a, b *pair) bool {
+	for i := range c.fields {
+		var t = util.Compare(a.vals[i], b.vals[i])
 		if t == 0 {
 			continue
 		}
 		if t < 0 {
-			return asc
+			return !c.desc[i]
 		}
-		return !asc
+		return c.desc[i]
 	}
-	return false
+	// keep natural order of equal items
+	return a.seq < b.seq
+}
+
+// topHeap implements heap.Interface with reversed order of sortIter.
+type topHeap sortIter
+
+func (h *topHeap) Len() int {
+	return len(h.data)
+}
+
+func (h *topHeap) Swap(i, j int) {
+	h.data[i], h.data[j] = h.data[j], h.data[i]
+}
+
+func (h *topHeap) Less(i, j int) bool {
+	return (*sortIter)(h).less(h.data[j], h.data[i])
+}
+
+func (h *topHeap) Push(x interface{}) {
+	h.data = append(h.data, x.(*pair))
+}
+
+func (h *topHeap) Pop() interface{} {
+	var n = len(h.data)
+	var x = h.data[n-1]
+	h.data = h.data[:n-1]
+	return x
 }
@@ -0,0 +1,202 @@
+package kv
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// sorted sequence of pairs
+type run interface {
+	next() (*pair, error)
+}
+
+type memRun struct {
+	data []*pair
+	idx  int
+}
+
+func (r *memRun) next() (*pair, error) {
+	if r.idx >= len(r.data) {
+		return nil, nil
+	}
+	var p = r.data[r.idx]
+	r.idx++
+	return p, nil
+}
+
+// fileRun is sorted run spilled to temporary file.
+// Each record is key, value and sequence number prefixed with varint lengths.
+type fileRun struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+func writeRun(data []*pair) (*fileRun, error) {
+	f, err := os.CreateTemp("", "nosql-sort-")
+	if err != nil {
+		return nil, err
+	}
+
+	var r = &fileRun{file: f}
+	var w = bufio.NewWriter(f)
+	var buf [binary.MaxVarintLen64]byte
+
+	var write = func(b []byte) {
+		if err != nil {
+			return
+		}
+		var n = binary.PutUvarint(buf[:], uint64(len(b)))
+		if _, err = w.Write(buf[:n]); err != nil {
+			return
+		}
+		_, err = w.Write(b)
+	}
+
+	for _, p := range data {
+		write(p.key)
+		write(p.value)
+		if err == nil {
+			var n = binary.PutUvarint(buf[:], p.seq)
+			_, err = w.Write(buf[:n])
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		r.close()
+		return nil, err
+	}
+
+	r.reader = bufio.NewReader(f)
+	return r, nil
+}
+
+func (r *fileRun) next() (*pair, error) {
+	key, err := r.read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	value, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	seq, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return nil, err
+	}
+	return &pair{key: key, value: value, seq: seq}, nil
+}
+
+func (r *fileRun) read() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return nil, err
+	}
+	var b = make([]byte, n)
+	_, err = io.ReadFull(r.reader, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *fileRun) close() error {
+	var name = r.file.Name()
+	var err = r.file.Close()
+	if e := os.Remove(name); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// mergeHeap merges sorted runs.
+type mergeHeap struct {
+	sort  *sortIter
+	heads []*pair
+	runs  []run
+}
+
+func (h *mergeHeap) add(r run) error {
+	p, err := h.pull(r)
+	if err != nil || p == nil {
+		return err
+	}
+	heap.Push(h, mergeItem{p, r})
+	return nil
+}
+
+// pull reads next pair of run with decoded sort values.
+func (h *mergeHeap) pull(r run) (*pair, error) {
+	p, err := r.next()
+	if err != nil || p == nil {
+		return nil, err
+	}
+	if p.vals == nil {
+		err = h.sort.decode(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (h *mergeHeap) next() (*pair, error) {
+	if len(h.heads) == 0 {
+		return nil, nil
+	}
+	var p = h.heads[0]
+	next, err := h.pull(h.runs[0])
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		heap.Pop(h)
+	} else {
+		h.heads[0] = next
+		heap.Fix(h, 0)
+	}
+	return p, nil
+}
+
+type mergeItem struct {
+	head *pair
+	run  run
+}
+
+// heap.Interface
+func (h *mergeHeap) Len() int {
+	return len(h.heads)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+	return h.sort.less(h.heads[i], h.heads[j])
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.heads[i], h.heads[j] = h.heads[j], h.heads[i]
+	h.runs[i], h.runs[j] = h.runs[j], h.runs[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	var t = x.(mergeItem)
+	h.heads = append(h.heads, t.head)
+	h.runs = append(h.runs, t.run)
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	var n = len(h.heads) - 1
+	var t = mergeItem{h.heads[n], h.runs[n]}
+	h.heads = h.heads[:n]
+	h.runs = h.runs[:n]
+	return t
+}
@@ -0,0 +1,241 @@
+package kv
+
+// Porter stemming algorithm for english words
+// (M.F. Porter, "An algorithm for suffix stripping", 1980).
+
+// stem returns stem of given lower case word.
+func stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			// not an english word
+			return word
+		}
+	}
+	var s = &stemmer{b: []byte(word)}
+	s.step1ab()
+	s.step1c()
+	s.step2()
+	s.step3()
+	s.step4()
+	s.step5()
+	return string(s.b)
+}
+
+type stemmer struct {
+	b []byte
+}
+
+// cons determines whether i-th letter is consonant.
+func (s *stemmer) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	}
+	return true
+}
+
+// measure returns number of vowel-consonant sequences in first n letters.
+func (s *stemmer) measure(n int) int {
+	var m, i = 0, 0
+	for i < n && s.cons(i) {
+		i++
+	}
+	for i < n {
+		for i < n && !s.cons(i) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		for i < n && s.cons(i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+// hasVowel determines whether first n letters contain vowel.
+func (s *stemmer) hasVowel(n int) bool {
+	for i := 0; i < n; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleCons determines whether first n letters end with double consonant.
+func (s *stemmer) doubleCons(n int) bool {
+	return n >= 2 && s.b[n-1] == s.b[n-2] && s.cons(n-1)
+}
+
+// cvc determines whether first n letters end with consonant-vowel-consonant
+// and the last consonant is not w, x or y (e.g. hop, but not snow).
+func (s *stemmer) cvc(n int) bool {
+	if n < 3 || !s.cons(n-1) || s.cons(n-2) || !s.cons(n-3) {
+		return false
+	}
+	var c = s.b[n-1]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func (s *stemmer) ends(suffix string) bool {
+	return len(s.b) >= len(suffix) && string(s.b[len(s.b)-len(suffix):]) == suffix
+}
+
+// stemLen returns length of word without given suffix.
+func (s *stemmer) stemLen(suffix string) int {
+	return len(s.b) - len(suffix)
+}
+
+func (s *stemmer) setTo(n int, repl string) {
+	s.b = append(s.b[:n], repl...)
+}
+
+// replace replaces the first matching suffix of rules if measure of stem is greater than m.
+func (s *stemmer) replace(rules [][2]string, m int) {
+	for _, r := range rules {
+		if s.ends(r[0]) {
+			var n = s.stemLen(r[0])
+			if s.measure(n) > m {
+				s.setTo(n, r[1])
+			}
+			return
+		}
+	}
+}
+
+// step1ab removes plurals and -ed or -ing.
+func (s *stemmer) step1ab() {
+	switch {
+	case s.ends("sses"), s.ends("ies"):
+		s.setTo(len(s.b)-2, "")
+	case s.ends("ss"):
+	case s.ends("s"):
+		s.setTo(len(s.b)-1, "")
+	}
+
+	if s.ends("eed") {
+		if s.measure(s.stemLen("eed")) > 0 {
+			s.setTo(len(s.b)-1, "")
+		}
+		return
+	}
+
+	var n int
+	switch {
+	case s.ends("ed") && s.hasVowel(s.stemLen("ed")):
+		n = s.stemLen("ed")
+	case s.ends("ing") && s.hasVowel(s.stemLen("ing")):
+		n = s.stemLen("ing")
+	default:
+		return
+	}
+	s.setTo(n, "")
+
+	switch {
+	case s.ends("at"), s.ends("bl"), s.ends("iz"):
+		s.setTo(len(s.b), "e")
+	case s.doubleCons(len(s.b)):
+		switch s.b[len(s.b)-1] {
+		case 'l', 's', 'z':
+		default:
+			s.setTo(len(s.b)-1, "")
+		}
+	case s.measure(len(s.b)) == 1 && s.cvc(len(s.b)):
+		s.setTo(len(s.b), "e")
+	}
+}
+
+// step1c turns terminal y to i when there is another vowel in the stem.
+func (s *stemmer) step1c() {
+	if s.ends("y") && s.hasVowel(s.stemLen("y")) {
+		s.b[len(s.b)-1] = 'i'
+	}
+}
+
+var step2Rules = [][2]string{
+	{"ational", "ate"},
+	{"tional", "tion"},
+	{"enci", "ence"},
+	{"anci", "ance"},
+	{"izer", "ize"},
+	{"bli", "ble"},
+	{"alli", "al"},
+	{"entli", "ent"},
+	{"eli", "e"},
+	{"ousli", "ous"},
+	{"ization", "ize"},
+	{"ation", "ate"},
+	{"ator", "ate"},
+	{"alism", "al"},
+	{"iveness", "ive"},
+	{"fulness", "ful"},
+	{"ousness", "ous"},
+	{"aliti", "al"},
+	{"iviti", "ive"},
+	{"biliti", "ble"},
+	{"logi", "log"},
+}
+
+// step2 maps double suffixes to single ones.
+func (s *stemmer) step2() {
+	s.replace(step2Rules, 0)
+}
+
+var step3Rules = [][2]string{
+	{"icate", "ic"},
+	{"ative", ""},
+	{"alize", "al"},
+	{"iciti", "ic"},
+	{"ical", "ic"},
+	{"ful", ""},
+	{"ness", ""},
+}
+
+// step3 deals with -ic-, -full, -ness etc.
+func (s *stemmer) step3() {
+	s.replace(step3Rules, 0)
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent",
+	"ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+// step4 removes suffixes of stems with measure greater than 1.
+func (s *stemmer) step4() {
+	for _, suffix := range step4Suffixes {
+		if !s.ends(suffix) {
+			continue
+		}
+		var n = s.stemLen(suffix)
+		if suffix == "ion" && (n == 0 || (s.b[n-1] != 's' && s.b[n-1] != 't')) {
+			continue
+		}
+		if s.measure(n) > 1 {
+			s.setTo(n, "")
+		}
+		return
+	}
+}
+
+// step5 removes final -e and reduces -ll to -l.
+func (s *stemmer) step5() {
+	if s.ends("e") {
+		var n = s.stemLen("e")
+		var m = s.measure(n)
+		if m > 1 || (m == 1 && !s.cvc(n)) {
+			s.setTo(n, "")
+		}
+	}
+	if s.ends("ll") && s.measure(len(s.b)) > 1 {
+		s.setTo(len(s.b)-1, "")
+	}
+}
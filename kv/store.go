@@ -11,7 +11,8 @@ import (
 var (
 	errNotFound     = errors.New("not found")
 	errNotSliceAddr = errors.New("result argument must be a slice address")
-	errNotString    = errors.New("value must have string type")
+	errNotKVStore   = errors.New("data store is not based on KV store")
+	errDuplicateID  = errors.New("duplicate id")
 )
 
 // New data store based on KV store.
@@ -26,13 +27,16 @@ func New(db Store) data.Store {
 
 type store struct {
 	sync.Mutex
-	db      Store
-	idxmeta map[reflect.Type][]idxmeta
+	data.IDGenerators
+	db         Store
+	idxmeta    map[reflect.Type][]idxmeta
+	migrated   map[string]bool
+	registered map[string]bool
 }
 
 // Collection returns collection by name.
 func (s *store) Collection(name string) data.Collection {
-	var tx, err = s.db.Begin(true)
+	var tx, err = s.begin()
 	if err != nil {
 		debug.Err("db.Begin", err)
 		panic(err)
@@ -45,6 +49,12 @@ func (s *store) Collection(name string) data.Collection {
 		panic(err)
 	}
 
+	err = s.migrate(tx, name)
+	if err != nil {
+		tx.Rollback()
+		panic(err)
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		debug.Err("tx.Commit", err)
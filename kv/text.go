@@ -0,0 +1,358 @@
+package kv
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/q"
+)
+
+// Full-text index of collection is inverted index kept in one bucket.
+// Posting list of term is a set of entries with keys made of term and
+// document id separated by zero byte (see idxKey), so postings of term are
+// adjacent and found with prefix scan. Value of entry is frequency of term
+// in text fields of document. Text fields are registered in metadata bucket.
+
+// txtName returns name of full-text index bucket of collection.
+func txtName(collection string) string {
+	return "txt_" + collection
+}
+
+func txtRegistryKey(collection string) []byte {
+	return []byte(collection + ".text")
+}
+
+// TextIndex declares text fields of collection and rebuilds its full-text index.
+func (s *store) TextIndex(collection string, fields ...string) error {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Bucket(collection, true)
+	if err != nil {
+		return err
+	}
+
+	err = s.migrate(tx, collection)
+	if err != nil {
+		return err
+	}
+
+	meta, err := tx.Bucket(metaBucket, true)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		err = meta.Delete(txtRegistryKey(collection))
+	} else {
+		err = meta.Set(txtRegistryKey(collection), []byte(strings.Join(fields, ",")))
+	}
+	if err != nil {
+		return err
+	}
+
+	err = buildText(tx, collection, fields)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// textFields returns text fields of collection.
+func textFields(tx Tx, collection string) ([]string, error) {
+	meta, err := tx.Bucket(metaBucket, false)
+	if meta == nil || err != nil {
+		return nil, err
+	}
+	v, err := meta.Get(txtRegistryKey(collection))
+	if len(v) == 0 || err != nil {
+		return nil, err
+	}
+	return strings.Split(string(v), ","), nil
+}
+
+// buildText rebuilds full-text index of collection.
+func buildText(tx Tx, collection string, fields []string) error {
+	bucket, err := tx.Bucket(collection, false)
+	if bucket == nil || err != nil {
+		if err != nil {
+			return err
+		}
+		return errNotFound
+	}
+
+	idx, err := tx.Bucket(txtName(collection), true)
+	if err != nil {
+		return err
+	}
+
+	err = clearBucket(idx)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var c = bucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		var data map[string]interface{}
+		err = unmarshal(v, &data)
+		if err != nil {
+			return err
+		}
+		err = setPostings(idx, keyID(k), termFreq(data, fields))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateText maintains full-text index of document, old is previous version of document.
+func (c *collectionIdx) updateText(tx Tx, id string, data, old map[string]interface{}) error {
+	fields, err := textFields(tx, c.name)
+	if len(fields) == 0 || err != nil {
+		return err
+	}
+
+	idx, err := tx.Bucket(txtName(c.name), true)
+	if err != nil {
+		return err
+	}
+
+	if old != nil {
+		err = deletePostings(idx, id, termFreq(old, fields))
+		if err != nil {
+			return err
+		}
+	}
+
+	return setPostings(idx, id, termFreq(data, fields))
+}
+
+// cleanText removes document from full-text index.
+func (c *collectionIdx) cleanText(tx Tx, id string, data map[string]interface{}) error {
+	fields, err := textFields(tx, c.name)
+	if len(fields) == 0 || err != nil {
+		return err
+	}
+
+	idx, err := tx.Bucket(txtName(c.name), false)
+	if idx == nil || err != nil {
+		return err
+	}
+
+	return deletePostings(idx, id, termFreq(data, fields))
+}
+
+func setPostings(idx Bucket, id string, terms map[string]int) error {
+	for term, n := range terms {
+		var err = idx.Set(idxKey(term, id), []byte(strconv.Itoa(n)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deletePostings(idx Bucket, id string, terms map[string]int) error {
+	for term := range terms {
+		var err = idx.Delete(idxKey(term, id))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postings returns frequencies of term by document id.
+func postings(idx Bucket, term string) map[string]int {
+	var prefix = idxKey(term, "")
+	var list = make(map[string]int)
+	var c = idx.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		n, _ := strconv.Atoi(string(v))
+		list[string(k[len(prefix):])] = n
+	}
+	return list
+}
+
+// termFreq returns frequencies of terms in text fields of document.
+func termFreq(data map[string]interface{}, fields []string) map[string]int {
+	var terms = make(map[string]int)
+	for _, f := range fields {
+		anyString(pathValue(data, f), func(s string) bool {
+			for _, t := range tokenize(s) {
+				terms[t]++
+			}
+			return false
+		})
+	}
+	return terms
+}
+
+// tokenize splits text to stemmed lower case terms skipping stop words.
+func tokenize(text string) []string {
+	var terms []string
+	var words = strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if stopWords[w] {
+			continue
+		}
+		terms = append(terms, stem(w))
+	}
+	return terms
+}
+
+// textQuery resolves text search conditions of filter using full-text index.
+type textQuery struct {
+	tx         Tx
+	collection string
+	// relevance of documents found by required text conditions
+	scores map[string]int
+}
+
+// resolve replaces text search conditions of filter with found documents.
+func (t *textQuery) resolve(filter []interface{}) ([]interface{}, error) {
+	var result = make([]interface{}, len(filter))
+	for i, c := range filter {
+		var r, err = t.condition(c, true)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = r
+	}
+	return result, nil
+}
+
+func (t *textQuery) condition(c interface{}, required bool) (interface{}, error) {
+	var err error
+	switch v := c.(type) {
+	case q.TextSearch:
+		return t.search(v, required)
+	case q.Not:
+		var r q.Not
+		r.Condition, err = t.condition(v.Condition, false)
+		return r, err
+	case q.And:
+		var r = make(q.And, len(v))
+		for i, e := range v {
+			r[i], err = t.condition(e, required)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	case q.Or:
+		var r = make(q.Or, len(v))
+		for i, e := range v {
+			r[i], err = t.condition(e, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+	return c, nil
+}
+
+// search finds documents with any or all terms of text search.
+func (t *textQuery) search(s q.TextSearch, required bool) (interface{}, error) {
+	fields, err := textFields(t.tx, t.collection)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, data.ErrNoTextIndex
+	}
+
+	var m = idsMatch{ids: make(hashset)}
+	idx, err := t.tx.Bucket(txtName(t.collection), false)
+	if idx == nil || err != nil {
+		return m, err
+	}
+
+	var terms = newHashset(tokenize(s.Search)).toArray()
+	if len(terms) == 0 {
+		return m, nil
+	}
+
+	var scores = make(map[string]int)
+	var count = make(map[string]int)
+	for _, term := range terms {
+		for id, n := range postings(idx, term) {
+			scores[id] += n
+			count[id]++
+		}
+	}
+
+	for id := range scores {
+		if s.All && count[id] < len(terms) {
+			delete(scores, id)
+			continue
+		}
+		m.ids.add(id)
+	}
+
+	if required {
+		if t.scores == nil {
+			t.scores = scores
+		} else {
+			for id, n := range scores {
+				t.scores[id] += n
+			}
+		}
+	}
+	return m, nil
+}
+
+// ranked returns ids of documents found by required text conditions in order of relevance.
+func (t *textQuery) ranked() keys {
+	var list = make(keys, 0, len(t.scores))
+	for id := range t.scores {
+		list = append(list, id)
+	}
+	sortIDs(list)
+	sort.SliceStable(list, func(i, j int) bool {
+		return t.scores[list[i]] > t.scores[list[j]]
+	})
+	return list
+}
+
+// english stop words
+var stopWords = map[string]bool{
+	"a": true, "about": true, "above": true, "after": true, "again": true, "against": true,
+	"all": true, "am": true, "an": true, "and": true, "any": true, "are": true, "as": true,
+	"at": true, "be": true, "because": true, "been": true, "before": true, "being": true,
+	"below": true, "between": true, "both": true, "but": true, "by": true, "can": true,
+	"did": true, "do": true, "does": true, "doing": true, "down": true, "during": true,
+	"each": true, "few": true, "for": true, "from": true, "further": true, "had": true,
+	"has": true, "have": true, "having": true, "he": true, "her": true, "here": true,
+	"hers": true, "herself": true, "him": true, "himself": true, "his": true, "how": true,
+	"i": true, "if": true, "in": true, "into": true, "is": true, "it": true, "its": true,
+	"itself": true, "just": true, "me": true, "more": true, "most": true, "my": true,
+	"myself": true, "no": true, "nor": true, "not": true, "now": true, "of": true,
+	"off": true, "on": true, "once": true, "only": true, "or": true, "other": true,
+	"our": true, "ours": true, "ourselves": true, "out": true, "over": true, "own": true,
+	"same": true, "she": true, "should": true, "so": true, "some": true, "such": true,
+	"than": true, "that": true, "the": true, "their": true, "theirs": true, "them": true,
+	"themselves": true, "then": true, "there": true, "these": true, "they": true,
+	"this": true, "those": true, "through": true, "to": true, "too": true, "under": true,
+	"until": true, "up": true, "very": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "while": true, "who": true,
+	"whom": true, "why": true, "will": true, "with": true, "you": true, "your": true,
+	"yours": true, "yourself": true, "yourselves": true,
+}
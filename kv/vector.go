@@ -0,0 +1,134 @@
+package kv
+
+import (
+	"sort"
+
+	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/q"
+)
+
+// splitVector returns vector search condition of filter and other conditions.
+// Vector search is top-level condition, it is allowed in top-level And too.
+func splitVector(filter []interface{}) (*q.VectorSearch, []interface{}) {
+	var vs *q.VectorSearch
+	var rest []interface{}
+	for _, c := range filter {
+		switch t := c.(type) {
+		case q.VectorSearch:
+			if vs != nil {
+				panic("invalid query")
+			}
+			vs = &t
+			continue
+		case q.And:
+			if v, r := splitVector(t); v != nil {
+				if vs != nil {
+					panic("invalid query")
+				}
+				vs = v
+				rest = append(rest, r...)
+				continue
+			}
+		}
+		rest = append(rest, c)
+	}
+	return vs, rest
+}
+
+// nearestVectors returns ids of documents nearest to vector of vector search in order of distance.
+// Documents are filtered by other conditions of query before search.
+// HNSW index of vector field is used if there are no other conditions.
+func nearestVectors(lp lookup, bucket Bucket, vs q.VectorSearch, rest []interface{}) (keys, error) {
+	if len(rest) == 0 && vs.K > 0 {
+		h, err := openHNSW(lp.tx, lp.collection.name, vs.Field)
+		if err != nil {
+			return nil, err
+		}
+		if h != nil && h.Metric == vs.Metric {
+			lp.use(vecName(lp.collection.name, vs.Field))
+			return h.search(vs.Vector, vs.K)
+		}
+	}
+
+	// exact search
+	var iter Iter
+	if len(rest) > 0 && lp.isSuitable(rest) {
+		var list = lp.find(rest)
+		sortIDs(list)
+		iter = KeysIter(bucket, list)
+	} else {
+		iter = FilterIter(bucket.Cursor(), rest)
+	}
+
+	var list []vectorCand
+	for {
+		ok, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		var doc map[string]interface{}
+		err = unmarshal(iter.Value(), &doc)
+		if err != nil {
+			return nil, err
+		}
+		vec, ok := toVector(pathValue(doc, vs.Field))
+		if !ok {
+			continue
+		}
+		list = append(list, vectorCand{
+			id: keyID(iter.Key()),
+			d:  data.VectorDistance(vs.Metric, vs.Vector, vec),
+		})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].d < list[j].d
+	})
+	if vs.K > 0 && len(list) > vs.K {
+		list = list[:vs.K]
+	}
+
+	var ids = make(keys, len(list))
+	for i, e := range list {
+		ids[i] = e.id
+	}
+	return ids, nil
+}
+
+// vectorCand is document with distance of its vector.
+type vectorCand struct {
+	id string
+	d  float64
+}
+
+// toVector converts JSON array of numbers to vector.
+func toVector(v interface{}) ([]float32, bool) {
+	a, ok := v.([]interface{})
+	if !ok || len(a) == 0 {
+		return nil, false
+	}
+	var vec = make([]float32, len(a))
+	for i, e := range a {
+		f, ok := e.(float64)
+		if !ok {
+			return nil, false
+		}
+		vec[i] = float32(f)
+	}
+	return vec, true
+}
+
+func equalVectors(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
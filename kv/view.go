@@ -3,8 +3,10 @@ package kv
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 
 	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/q"
 	"github.com/gocontrib/nosql/reflection"
 )
 
@@ -45,6 +47,7 @@ func (v *view) One(result interface{}) error {
 	if err != nil {
 		return err
 	}
+	defer c.Close()
 	if !c.next() {
 		return errNotFound
 	}
@@ -102,7 +105,7 @@ func (v *view) unmarshal(c *cursor, result interface{}, meta *reflection.Meta) e
 	if meta == nil {
 		meta = reflection.GetMeta(result)
 	}
-	meta.SetID(result, string(c.key()))
+	meta.SetID(result, keyID(c.key()))
 	return nil
 }
 
@@ -132,40 +135,226 @@ func (v *view) Cursor() (data.Cursor, error) {
 	return v.cursor(false)
 }
 
-func (v *view) cursor(writeable bool) (*cursor, error) {
-	var db = v.collection.db
-	var tx, err = db.Begin(writeable)
+// Explain describes access path, used indexes, sort strategy and residual filter of query.
+// Indexes are looked up and text or vector searches are done, but documents are not read.
+func (v *view) Explain() (data.Plan, error) {
+	var plan data.Plan
+	var tx, err = v.collection.db.Begin(false)
 	if err != nil {
-		return nil, err
+		return plan, err
 	}
+	defer tx.Rollback()
 
 	bucket, err := tx.Bucket(v.collection.name, false)
-	if bucket == nil || err != nil {
+	if err != nil {
+		return plan, err
+	}
+	if bucket == nil {
+		return plan, errNotFound
+	}
+	_, err = v.iter(tx, bucket, &plan)
+	return plan, err
+}
+
+// minimal number of found keys to prefer walking index of sort field over sorting of found documents
+const idxSortMinKeys = 1000
+
+// sortField returns the only sort field and its direction.
+func (v *view) sortField() (string, bool) {
+	if len(v.sort) != 1 {
+		return "", false
+	}
+	var field = v.sort[0]
+	var desc = strings.HasPrefix(field, "-")
+	if desc {
+		field = field[1:]
+	}
+	if field == "_id" {
+		field = "id"
+	}
+	return field, desc
+}
+
+// iter makes iterator of documents in order of filter -> sort -> skip -> limit.
+// Chosen access path and sort strategy are described by given plan unless it is nil.
+func (v *view) iter(tx Tx, bucket Bucket, plan *data.Plan) (Iter, error) {
+	var top int64
+	if v.limit > 0 {
+		top = v.skip + v.limit
+	}
+
+	var lp = lookup{
+		collection: v.collection,
+		tx:         tx,
+	}
+	if plan != nil {
+		lp.indexes = make(hashset)
+		defer func() {
+			plan.Indexes = lp.indexes.toArray()
+		}()
+	}
+
+	filter, ok := normalize(v.filter)
+	if !ok {
+		describe(plan, "none", "none", nil)
+		return KeysIter(bucket, emptyKeys), nil
+	}
+	if err := checkRegex(filter); err != nil {
+		return nil, err
+	}
+
+	var text = &textQuery{
+		tx:         tx,
+		collection: v.collection.name,
+	}
+	filter, err := text.resolve(filter)
+	if err != nil {
+		return nil, err
+	}
+	if text.scores != nil {
+		lp.use(txtName(v.collection.name))
+	}
+
+	// ids of documents in order of relevance or distance
+	var ranked keys
+	if vs, rest := splitVector(filter); vs != nil {
+		ranked, err = nearestVectors(lp, bucket, *vs, rest)
 		if err != nil {
 			return nil, err
 		}
-		return nil, errNotFound
+		filter = append(rest, idsMatch{ids: newHashset(ranked)})
+	} else if text.scores != nil {
+		ranked = text.ranked()
+	}
+
+	var keys keys
+	var useKeys = len(filter) > 0 && lp.isSuitable(filter)
+	if useKeys {
+		keys = lp.find(filter)
+		sortIDs(keys)
 	}
 
 	var iter Iter
+	var field, desc = v.sortField()
 
-	if len(v.filter) > 0 {
-		var lp = lookup{
-			collection: v.collection,
-			tx:         tx,
+	switch {
+	case len(v.sort) == 0 && ranked != nil:
+		// order of documents found by vector or text search
+		iter = FilterIter(&keysCursor{bucket: bucket, keys: ranked}, filter)
+		describe(plan, "lookup", "rank", filter)
+	case field == "id" && useKeys:
+		// found keys are sorted in order of bucket keys
+		if desc {
+			for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
 		}
-		if lp.isSuitable(v.filter) {
-			var keys = lp.find(v.filter)
-			iter = KeysIter(bucket.Cursor(), keys, v.limit, v.skip)
+		iter = KeysIter(bucket, keys)
+		describe(plan, "lookup", "keys", nil)
+	case field == "id":
+		// natural order of keys
+		var c = bucket.Cursor()
+		if desc {
+			c = &reverseCursor{c}
 		}
+		iter = FilterIter(c, filter)
+		describe(plan, "scan", "keys", filter)
+	case len(field) > 0 && hasIdx(tx, v.collection.name, field) && (!useKeys || len(keys) >= idxSortMinKeys) &&
+		idxComplete(tx, bucket, idxName(v.collection.name, field)):
+		// walk index of sort field
+		idx, err := tx.Bucket(idxName(v.collection.name, field), false)
+		if err != nil {
+			return nil, err
+		}
+		lp.use(idxName(v.collection.name, field))
+		var ic = &idxCursor{
+			idx:    idx,
+			bucket: bucket,
+		}
+		if useKeys {
+			ic.ids = newHashset(keys)
+			filter = nil
+		}
+		var c Cursor = ic
+		if desc {
+			c = &reverseCursor{c}
+		}
+		iter = FilterIter(c, filter)
+		describe(plan, "index", "index", filter)
+	default:
+		var access = "scan"
+		if useKeys {
+			access = "lookup"
+			iter = KeysIter(bucket, keys)
+		} else {
+			iter = FilterIter(bucket.Cursor(), filter)
+		}
+		iter = SortIter(iter, v.sort, top)
+		var order = "none"
+		if len(v.sort) > 0 {
+			order = "memory"
+		}
+		if useKeys {
+			describe(plan, access, order, nil)
+		} else {
+			describe(plan, access, order, filter)
+		}
+	}
+
+	if name, near, ok := nearCondition(filter); ok && len(v.sort) == 0 && ranked == nil {
+		// order by distance
+		iter = NearIter(iter, name, near.Lng, near.Lat)
+		if plan != nil {
+			plan.Sort = "distance"
+		}
+	}
+
+	return LimitIter(iter, v.skip, v.limit), nil
+}
+
+// describe sets access path, sort strategy and residual filter of plan unless it is nil.
+func describe(plan *data.Plan, access, order string, filter []interface{}) {
+	if plan == nil {
+		return
+	}
+	plan.Access = access
+	plan.Sort = order
+	// ids found by text or vector search are described by indexes
+	var list q.And
+	for _, c := range filter {
+		if _, ok := c.(idsMatch); !ok {
+			list = append(list, c)
+		}
+	}
+	switch len(list) {
+	case 0:
+		plan.Filter = nil
+	case 1:
+		plan.Filter = list[0]
+	default:
+		plan.Filter = list
+	}
+}
+
+func (v *view) cursor(writeable bool) (*cursor, error) {
+	var db = v.collection.db
+	var tx, err = db.Begin(writeable)
+	if err != nil {
+		return nil, err
 	}
 
-	if iter == nil {
-		iter = FilterIter(bucket.Cursor(), v.filter, v.limit, v.skip)
+	bucket, err := tx.Bucket(v.collection.name, false)
+	if bucket == nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errNotFound
 	}
 
-	if len(v.sort) > 0 {
-		iter = SortIter(iter, v.sort)
+	iter, err := v.iter(tx, bucket, nil)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	var result = &cursor{
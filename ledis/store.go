@@ -2,6 +2,7 @@ package ledis
 
 import (
 	"os"
+	"regexp"
 	"strconv"
 
 	"github.com/gocontrib/log"
@@ -101,14 +102,13 @@ func (s *store) Incr(k []byte) (int64, error) {
 func (s *store) Scan(prefix string, cursor int, count int, last []byte) (int, [][]byte, error) {
 	var inclusive = false
 	if cursor == 0 {
-		last = makeKey(prefix, 1)
+		last = []byte(prefix)
 		inclusive = true
 	}
 
-	var match = "^" + prefix
-	keys, err := s.db.Scan(ledis.KV, last, count, inclusive, match)
+	keys, err := s.ScanFrom(prefix, last, count, inclusive)
 	if err != nil {
-		return 0, nil, debug.Err("scan", err)
+		return 0, nil, err
 	}
 
 	var next = cursor + 1
@@ -119,6 +119,38 @@ func (s *store) Scan(prefix string, cursor int, count int, last []byte) (int, []
 	return next, keys, nil
 }
 
-func makeKey(prefix string, id int64) []byte {
-	return []byte(prefix + strconv.FormatInt(id, 10))
+// ScanFrom scans keys with given prefix in ascending order.
+func (s *store) ScanFrom(prefix string, start []byte, count int, inclusive bool) ([][]byte, error) {
+	var match = "^" + regexp.QuoteMeta(prefix)
+	keys, err := s.db.Scan(ledis.KV, start, count, inclusive, match)
+	if err != nil {
+		return nil, debug.Err("scan", err)
+	}
+	return keys, nil
+}
+
+// RevScanFrom scans keys with given prefix in descending order.
+func (s *store) RevScanFrom(prefix string, start []byte, count int, inclusive bool) ([][]byte, error) {
+	if start == nil {
+		start = prefixEnd(prefix)
+		inclusive = false
+	}
+	var match = "^" + regexp.QuoteMeta(prefix)
+	keys, err := s.db.RevScan(ledis.KV, start, count, inclusive, match)
+	if err != nil {
+		return nil, debug.Err("revscan", err)
+	}
+	return keys, nil
+}
+
+// prefixEnd returns the least key greater than all keys with given prefix.
+func prefixEnd(prefix string) []byte {
+	var end = []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
 }
@@ -32,8 +32,15 @@ func (c *collection) Count() (int64, error) {
 func (c *collection) Insert(docs ...interface{}) error {
 	var now = time.Now().UTC()
 	for _, doc := range docs {
+		id, err := c.store.NewID(c.name, doc)
+		if err != nil {
+			return err
+		}
+		if len(id) == 0 {
+			id = bson.NewObjectId().Hex()
+		}
 		var meta = reflection.GetMeta(doc)
-		meta.SetID(doc, bson.NewObjectId().Hex())
+		meta.SetID(doc, id)
 		meta.SetCreatedAt(doc, now)
 		meta.SetUpdatedAt(doc, now)
 	}
@@ -76,7 +83,11 @@ func (c *collection) Update(selector interface{}, doc interface{}) error {
 	if ok {
 		return collection.UpdateId(id, doc)
 	} else {
-		return collection.Update(mongoFilter([]interface{}{selector}), doc)
+		filter, err := mongoFilter([]interface{}{selector})
+		if err != nil {
+			return err
+		}
+		return collection.Update(filter, doc)
 	}
 }
 
@@ -93,7 +104,12 @@ func (c *collection) Delete(selector interface{}) error {
 	if selector == nil {
 		_, err = collection.RemoveAll(nil)
 	} else {
-		_, err = collection.RemoveAll(mongoFilter([]interface{}{selector}))
+		var filter bson.M
+		filter, err = mongoFilter([]interface{}{selector})
+		if err != nil {
+			return err
+		}
+		_, err = collection.RemoveAll(filter)
 	}
 	return err
 }
@@ -1,65 +1,67 @@
 package mongo
 
 import (
+	"github.com/gocontrib/nosql"
 	"github.com/gocontrib/nosql/q"
 	"gopkg.in/mgo.v2/bson"
 )
 
-func mongoFilter(filter []interface{}) bson.M {
-	if len(filter) == 0 {
-		return nil
+// mongoFilter makes query of canonical filter (see q.Normalize).
+func mongoFilter(filter []interface{}) (bson.M, error) {
+	var f, ok = q.Normalize(q.And(filter))
+	if !ok {
+		// contradiction matches no documents
+		return bson.M{"_id": bson.M{"$in": []interface{}{}}}, nil
 	}
-	if len(filter) == 1 {
-		return mongoCondition(filter[0])
+	if f == nil {
+		return nil, nil
 	}
-	var conds []bson.M
-	for _, v := range filter {
-		conds = append(conds, mongoCondition(v))
+	doc, err := q.ToDocument(f)
+	if err != nil {
+		return nil, err
 	}
-	return bson.M{"$and": conds}
+	return mongoDocument(doc), nil
 }
 
-func mongoCondition(c interface{}) bson.M {
-	switch t := c.(type) {
-	case q.Not:
-		return bson.M{"$not": t.Condition}
-	case q.And:
-		var conds []bson.M
-		for _, v := range t {
-			conds = append(conds, mongoCondition(v))
-		}
-		return bson.M{"$and": conds}
-	case q.Or:
-		var conds []bson.M
-		for _, v := range t {
-			conds = append(conds, mongoCondition(v))
-		}
-		return bson.M{"$or": conds}
-	case q.M:
-		var m = bson.M{}
-		for field, value := range t {
-			var key = field
-			if field == "id" {
-				key = "_id"
+// mongoDocument renames id fields to _id and sets language of text search
+// in document made by q.ToDocument.
+func mongoDocument(doc map[string]interface{}) bson.M {
+	var m = bson.M{}
+	for k, v := range doc {
+		switch k {
+		case "id":
+			m["_id"] = v
+		case "$and", "$or", "$nor":
+			var list = v.([]interface{})
+			var conds = make([]bson.M, len(list))
+			for i, c := range list {
+				conds[i] = mongoDocument(c.(map[string]interface{}))
+			}
+			m[k] = conds
+		case "$text":
+			var text = bson.M{"$language": data.TextLanguage}
+			for k, e := range v.(map[string]interface{}) {
+				text[k] = e
 			}
-			m[key] = mongoOp(value)
+			m[k] = text
+		default:
+			m[k] = v
 		}
-		return m
-	default:
-		panic("invalid query")
 	}
+	return m
 }
 
-func mongoOp(v interface{}) interface{} {
-	switch t := v.(type) {
-	case q.Op:
-		var op = "$" + string(t.Kind)
-		return bson.M{op: t.Value}
-	case q.In:
-		return bson.M{"$in": []interface{}(t)}
-	case q.NotIn:
-		return bson.M{"$nin": []interface{}(t)}
-	default:
-		return v
+// hasTextSearch determines whether filter requires text search.
+func hasTextSearch(filter []interface{}) bool {
+	for _, c := range filter {
+		switch t := c.(type) {
+		case q.TextSearch:
+			return true
+		case q.And:
+			if hasTextSearch(t) {
+				return true
+			}
+		}
 	}
+	return false
 }
@@ -35,6 +35,7 @@ func Open(url, databaseName string, dropDatabase bool) (data.Store, error) {
 }
 
 type store struct {
+	data.IDGenerators
 	session *mgo.Session
 	dbname  string
 }
@@ -44,6 +45,35 @@ func (s *store) Collection(name string) data.Collection {
 	return &collection{s, name}
 }
 
+// TextIndex declares text fields of collection creating text index of them.
+// Mongo supports one text index per collection.
+func (s *store) TextIndex(collection string, fields ...string) error {
+	var session = s.session.Copy()
+	defer session.Close()
+	var key []string
+	for _, f := range fields {
+		key = append(key, "$text:"+f)
+	}
+	return session.DB(s.dbname).C(collection).EnsureIndex(mgo.Index{
+		Key:             key,
+		DefaultLanguage: data.TextLanguage,
+	})
+}
+
+// GeoIndex creates 2dsphere indexes of given point fields of collection.
+func (s *store) GeoIndex(collection string, fields ...string) error {
+	var session = s.session.Copy()
+	defer session.Close()
+	var c = session.DB(s.dbname).C(collection)
+	for _, f := range fields {
+		var err = c.EnsureIndexKey("$2dsphere:" + f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close performs cleanups.
 func (s *store) Close() error {
 	s.session.Close()
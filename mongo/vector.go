@@ -0,0 +1,162 @@
+package mongo
+
+import (
+	"math"
+	"strings"
+
+	"github.com/gocontrib/nosql/q"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// name of computed distance of vector search results
+const vectorDistance = "_distance"
+
+// VectorIndex does nothing, mongo finds nearest vectors by exact search in aggregation pipeline.
+func (s *store) VectorIndex(collection, field string, dims int, metric q.VectorMetric) error {
+	return nil
+}
+
+// splitVector returns vector search condition of filter and other conditions.
+// Vector search is top-level condition, it is allowed in top-level And too.
+func splitVector(filter []interface{}) (*q.VectorSearch, []interface{}) {
+	var vs *q.VectorSearch
+	var rest []interface{}
+	for _, c := range filter {
+		switch t := c.(type) {
+		case q.VectorSearch:
+			if vs != nil {
+				panic("invalid query")
+			}
+			vs = &t
+			continue
+		case q.And:
+			if v, r := splitVector(t); v != nil {
+				if vs != nil {
+					panic("invalid query")
+				}
+				vs = v
+				rest = append(rest, r...)
+				continue
+			}
+		}
+		rest = append(rest, c)
+	}
+	return vs, rest
+}
+
+// pipe makes aggregation pipeline of query with vector search.
+func (r *view) pipe(session *mgo.Session, vs q.VectorSearch, filter []interface{}, count bool) (*mgo.Pipe, error) {
+	stages, err := r.stages(vs, filter, count)
+	if err != nil {
+		return nil, err
+	}
+	var db = session.DB(r.collection.store.dbname)
+	var collection = db.C(r.collection.name)
+	return collection.Pipe(stages), nil
+}
+
+// stages makes stages of aggregation pipeline of query with vector search.
+// Documents are ordered by distance unless sorted explicitly.
+func (r *view) stages(vs q.VectorSearch, filter []interface{}, count bool) ([]bson.M, error) {
+	var stages []bson.M
+	if len(filter) > 0 {
+		match, err := mongoFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		// text search should be the first stage
+		stages = append(stages, bson.M{"$match": match})
+	}
+	stages = append(stages,
+		// non-empty arrays
+		bson.M{"$match": bson.M{vs.Field + ".0": bson.M{"$exists": true}}},
+		bson.M{"$addFields": bson.M{vectorDistance: mongoDistance("$"+vs.Field, vs.Vector, vs.Metric)}},
+		bson.M{"$sort": bson.D{{Name: vectorDistance, Value: 1}, {Name: "_id", Value: 1}}},
+	)
+	if vs.K > 0 {
+		stages = append(stages, bson.M{"$limit": vs.K})
+	}
+	if count {
+		return append(stages, bson.M{"$count": "n"}), nil
+	}
+	if len(r.sort) > 0 {
+		stages = append(stages, bson.M{"$sort": mongoSort(r.sort)})
+	}
+	if r.skip > 0 {
+		stages = append(stages, bson.M{"$skip": r.skip})
+	}
+	if r.limit > 0 {
+		stages = append(stages, bson.M{"$limit": r.limit})
+	}
+	return append(stages, bson.M{"$project": bson.M{vectorDistance: 0}}), nil
+}
+
+// mongoSort makes $sort stage document of sort fields.
+func mongoSort(fields []string) bson.D {
+	var doc bson.D
+	for _, f := range fields {
+		var order = 1
+		if strings.HasPrefix(f, "-") {
+			order = -1
+			f = f[1:]
+		} else {
+			f = strings.TrimPrefix(f, "+")
+		}
+		if f == "id" {
+			f = "_id"
+		}
+		doc = append(doc, bson.DocElem{Name: f, Value: order})
+	}
+	return doc
+}
+
+// mongoDistance makes aggregation expression of distance between array field and vector,
+// it is the same as data.VectorDistance.
+func mongoDistance(field string, vec []float32, metric q.VectorMetric) interface{} {
+	var v = make([]float64, len(vec))
+	var norm float64
+	for i, x := range vec {
+		v[i] = float64(x)
+		norm += v[i] * v[i]
+	}
+	norm = math.Sqrt(norm)
+
+	// sum of given expression of elements of field and vector, "$$this" is element index
+	var sum = func(expr func(a, b interface{}) interface{}) interface{} {
+		return bson.M{"$reduce": bson.M{
+			"input":        bson.M{"$range": []interface{}{0, bson.M{"$min": []interface{}{bson.M{"$size": field}, len(v)}}}},
+			"initialValue": 0,
+			"in": bson.M{"$add": []interface{}{"$$value", expr(
+				bson.M{"$arrayElemAt": []interface{}{field, "$$this"}},
+				bson.M{"$arrayElemAt": []interface{}{v, "$$this"}},
+			)}},
+		}}
+	}
+	var mul = func(a, b interface{}) interface{} {
+		return bson.M{"$multiply": []interface{}{a, b}}
+	}
+	var dot = sum(mul)
+
+	switch metric {
+	case q.Euclidean:
+		return bson.M{"$sqrt": sum(func(a, b interface{}) interface{} {
+			var d = bson.M{"$subtract": []interface{}{a, b}}
+			return mul(d, d)
+		})}
+	case q.DotProduct:
+		return bson.M{"$multiply": []interface{}{-1, dot}}
+	default:
+		if norm == 0 {
+			return 1
+		}
+		var fieldNorm = bson.M{"$sqrt": sum(func(a, b interface{}) interface{} {
+			return mul(a, a)
+		})}
+		return bson.M{"$cond": []interface{}{
+			bson.M{"$eq": []interface{}{fieldNorm, 0}},
+			1,
+			bson.M{"$subtract": []interface{}{1, bson.M{"$divide": []interface{}{dot, mul(fieldNorm, norm)}}}},
+		}}
+	}
+}
@@ -3,8 +3,12 @@ package mongo
 import (
 	"github.com/gocontrib/nosql"
 	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
 )
 
+// name of projected relevance of text search results
+const textScore = "_score"
+
 type view struct {
 	collection *collection
 	filter     []interface{}
@@ -27,10 +31,14 @@ func (r *view) session() *mgo.Session {
 	return r.collection.store.session.Copy()
 }
 
-func (r *view) query(session *mgo.Session) *mgo.Query {
+func (r *view) query(session *mgo.Session) (*mgo.Query, error) {
+	filter, err := mongoFilter(r.filter)
+	if err != nil {
+		return nil, err
+	}
 	var db = session.DB(r.collection.store.dbname)
 	var collection = db.C(r.collection.name)
-	var query = collection.Find(mongoFilter(r.filter))
+	var query = collection.Find(filter)
 	if r.skip > 0 {
 		query = query.Skip(r.skip)
 	}
@@ -39,16 +47,36 @@ func (r *view) query(session *mgo.Session) *mgo.Query {
 	}
 	if len(r.sort) > 0 {
 		query = query.Sort(r.sort...)
+	} else if hasTextSearch(r.filter) {
+		// relevance order
+		query = query.Select(bson.M{textScore: bson.M{"$meta": "textScore"}}).Sort("$textScore:" + textScore)
 	}
-	return query
+	return query, nil
 }
 
 // Count returns the number of items that match the set conditions.
 func (r *view) Count() (int64, error) {
 	var s = r.session()
 	defer s.Close()
-	var query = r.query(s)
-	var n, err = query.Count()
+	if vs, rest := splitVector(r.filter); vs != nil {
+		var result struct {
+			N int64 `bson:"n"`
+		}
+		pipe, err := r.pipe(s, *vs, rest, true)
+		if err != nil {
+			return 0, err
+		}
+		err = pipe.One(&result)
+		if err == mgo.ErrNotFound {
+			return 0, nil
+		}
+		return result.N, err
+	}
+	query, err := r.query(s)
+	if err != nil {
+		return 0, err
+	}
+	n, err := query.Count()
 	return int64(n), err
 }
 
@@ -56,14 +84,36 @@ func (r *view) Count() (int64, error) {
 func (r *view) One(result interface{}) error {
 	var s = r.session()
 	defer s.Close()
-	return r.query(s).One(result)
+	if vs, rest := splitVector(r.filter); vs != nil {
+		pipe, err := r.pipe(s, *vs, rest, false)
+		if err != nil {
+			return err
+		}
+		return pipe.One(result)
+	}
+	query, err := r.query(s)
+	if err != nil {
+		return err
+	}
+	return query.One(result)
 }
 
 // All fetches all results within the result set.
 func (r *view) All(result interface{}) error {
 	var s = r.session()
 	defer s.Close()
-	return r.query(s).All(result)
+	if vs, rest := splitVector(r.filter); vs != nil {
+		pipe, err := r.pipe(s, *vs, rest, false)
+		if err != nil {
+			return err
+		}
+		return pipe.All(result)
+	}
+	query, err := r.query(s)
+	if err != nil {
+		return err
+	}
+	return query.All(result)
 }
 
 // Limit defines the maximum number of results in this set.
@@ -93,10 +143,97 @@ func (r *view) Sort(fields ...string) data.Result {
 // Cursor executes query and returns cursor capable of going over all the results.
 func (r *view) Cursor() (data.Cursor, error) {
 	var s = r.session()
-	var iter = r.query(s).Iter()
+	var iter *mgo.Iter
+	if vs, rest := splitVector(r.filter); vs != nil {
+		pipe, err := r.pipe(s, *vs, rest, false)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		iter = pipe.Iter()
+	} else {
+		query, err := r.query(s)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		iter = query.Iter()
+	}
 	var err = iter.Err()
 	if err != nil {
 		return nil, err
 	}
 	return &cursor{iter}, nil
 }
+
+// Explain returns JSON of find command (or of aggregation pipeline of vector search)
+// and output of native explain.
+func (r *view) Explain() (data.Plan, error) {
+	var plan data.Plan
+	var s = r.session()
+	defer s.Close()
+
+	var vs, rest = splitVector(r.filter)
+	var cmd bson.D
+	if vs != nil {
+		stages, err := r.stages(*vs, rest, false)
+		if err != nil {
+			return plan, err
+		}
+		cmd = bson.D{
+			{Name: "aggregate", Value: r.collection.name},
+			{Name: "pipeline", Value: stages},
+		}
+	} else {
+		filter, err := mongoFilter(r.filter)
+		if err != nil {
+			return plan, err
+		}
+		cmd = bson.D{
+			{Name: "find", Value: r.collection.name},
+			{Name: "filter", Value: filter},
+		}
+		if len(r.sort) > 0 {
+			cmd = append(cmd, bson.DocElem{Name: "sort", Value: mongoSort(r.sort)})
+		} else if hasTextSearch(r.filter) {
+			cmd = append(cmd, bson.DocElem{Name: "sort", Value: bson.M{textScore: bson.M{"$meta": "textScore"}}})
+		}
+		if r.skip > 0 {
+			cmd = append(cmd, bson.DocElem{Name: "skip", Value: r.skip})
+		}
+		if r.limit > 0 {
+			cmd = append(cmd, bson.DocElem{Name: "limit", Value: r.limit})
+		}
+	}
+	b, err := bson.MarshalJSON(cmd)
+	if err != nil {
+		return plan, err
+	}
+	plan.Statement = string(b)
+
+	var result bson.M
+	if vs != nil {
+		var pipe *mgo.Pipe
+		pipe, err = r.pipe(s, *vs, rest, false)
+		if err != nil {
+			return plan, err
+		}
+		err = pipe.Explain(&result)
+	} else {
+		var query *mgo.Query
+		query, err = r.query(s)
+		if err != nil {
+			return plan, err
+		}
+		err = query.Explain(&result)
+	}
+	if err != nil {
+		return plan, err
+	}
+	b, err = bson.MarshalJSON(result)
+	if err != nil {
+		return plan, err
+	}
+	plan.Explain = string(b)
+	return plan, nil
+}
@@ -0,0 +1,127 @@
+package postgresql
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/gocontrib/nosql"
+)
+
+// StmtCacheSize is maximum number of prepared statements cached per collection,
+// statements of other queries are prepared for single use.
+var StmtCacheSize = 256
+
+// CacheStats are counters of prepared statements cache of store.
+type CacheStats struct {
+	// Hits is number of queries executed with cached statement.
+	Hits int64
+	// Misses is number of queries which statement was prepared.
+	Misses int64
+	// Statements is number of cached statements.
+	Statements int
+}
+
+// stmtCache holds prepared statements by collection and text of statement.
+// All values of filters are parameters, so text of statement is shape of filter
+// (fields and operators without values) together with sort, limit and offset.
+type stmtCache struct {
+	mu     sync.Mutex
+	stmts  map[string]map[string]*sql.Stmt
+	hits   int64
+	misses int64
+}
+
+// get returns cached prepared statement or prepares new one, function closes statement
+// that is not cached.
+func (c *stmtCache) get(db *sql.DB, collection, query string) (*sql.Stmt, func(), error) {
+	c.mu.Lock()
+	if stmt, ok := c.stmts[collection][query]; ok {
+		c.hits++
+		c.mu.Unlock()
+		return stmt, func() {}, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.stmts[collection][query]; ok {
+		// prepared concurrently
+		stmt.Close()
+		return cached, func() {}, nil
+	}
+	if len(c.stmts[collection]) >= StmtCacheSize {
+		return stmt, func() { stmt.Close() }, nil
+	}
+	if c.stmts == nil {
+		c.stmts = make(map[string]map[string]*sql.Stmt)
+	}
+	if c.stmts[collection] == nil {
+		c.stmts[collection] = make(map[string]*sql.Stmt)
+	}
+	c.stmts[collection][query] = stmt
+	return stmt, func() {}, nil
+}
+
+// drop closes cached statements of collection.
+func (c *stmtCache) drop(collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stmt := range c.stmts[collection] {
+		stmt.Close()
+	}
+	delete(c.stmts, collection)
+}
+
+// close closes all cached statements.
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range c.stmts {
+		for _, stmt := range m {
+			stmt.Close()
+		}
+	}
+	c.stmts = nil
+}
+
+func (c *stmtCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n = 0
+	for _, m := range c.stmts {
+		n += len(m)
+	}
+	return CacheStats{Hits: c.hits, Misses: c.misses, Statements: n}
+}
+
+// StmtCacheStats returns counters of prepared statements cache of store.
+func StmtCacheStats(ds data.Store) (CacheStats, error) {
+	s, ok := ds.(*store)
+	if !ok {
+		return CacheStats{}, errNotPgStore
+	}
+	return s.stmts.stats(), nil
+}
+
+// DropCollection drops table of collection and its cached statements,
+// the table is created again on next use of collection.
+func DropCollection(ds data.Store, collection string) error {
+	s, ok := ds.(*store)
+	if !ok {
+		return errNotPgStore
+	}
+	_, err := s.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", pgIdent(collection)))
+	if err != nil {
+		return err
+	}
+	s.stmts.drop(collection)
+	s.tables.Delete(collection)
+	return nil
+}
@@ -6,8 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
-	"sync"
 	"time"
 
 	"github.com/gocontrib/nosql"
@@ -16,27 +14,63 @@ import (
 )
 
 type collection struct {
-	sync.Mutex
-	store   *store
-	db      *sql.DB
-	name    string
-	created bool
+	store *store
+	db    *sql.DB
+	name  string
+	// whether id column holds generated text ids instead of SERIAL
+	textID bool
 }
 
 func (c *collection) init() error {
-	c.Lock()
-	defer c.Unlock()
-	if c.created {
+	if textID, ok := c.store.tables.Load(c.name); ok && textID.(bool) == c.textID {
 		return nil
 	}
-	const schema = "(id SERIAL PRIMARY KEY, data jsonb)"
-	var _, err = c.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", c.name, schema))
+	var schema = "(id SERIAL PRIMARY KEY, data jsonb)"
+	if c.textID {
+		schema = "(id TEXT PRIMARY KEY, data jsonb)"
+	}
+	var _, err = c.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", pgIdent(c.name), schema))
 	if err != nil {
 		return err
 	}
-	c.created = true
-	_, err = c.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s on %s using GIN(data jsonb_path_ops)", c.name, c.name))
-	return err
+	err = c.migrateID()
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN(data jsonb_path_ops)",
+		pgIdent("idx_"+c.name), pgIdent(c.name)))
+	if err != nil {
+		return err
+	}
+	c.store.tables.Store(c.name, c.textID)
+	return nil
+}
+
+// migrateID converts SERIAL id column of existing table to TEXT when id generator
+// of collection is switched to generated text ids, existing ids are kept as text.
+// Text ids cannot be converted back to SERIAL.
+func (c *collection) migrateID() error {
+	var typ string
+	var err = c.db.QueryRow(`SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'id'`, c.name).Scan(&typ)
+	if err != nil {
+		return err
+	}
+	var textID = typ == "text"
+	if textID == c.textID {
+		return nil
+	}
+	if textID {
+		return fmt.Errorf("collection %s has text ids, they cannot be converted to native ids", c.name)
+	}
+	_, err = c.db.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN id DROP DEFAULT, ALTER COLUMN id TYPE TEXT USING id::text",
+		pgIdent(c.name)))
+	if err != nil {
+		return err
+	}
+	// statements prepared with SERIAL column have to be prepared again
+	c.store.stmts.drop(c.name)
+	return nil
 }
 
 func (c *collection) Exec(query string, args ...interface{}) (sql.Result, error) {
@@ -72,14 +106,53 @@ func (c *collection) QueryRow(query string, args ...interface{}) (*sql.Row, erro
 	return c.db.QueryRow(query, args...), nil
 }
 
+// querySelect executes select statement made by query (see makeSelectStmt)
+// with cached prepared statement (see StmtCacheStats).
+func (c *collection) querySelect(query string, args ...interface{}) (*sql.Rows, error) {
+	var err = c.init()
+	if err != nil {
+		return nil, err
+	}
+	if debug.Enabled() {
+		debug.Debug("%s; args: %v", query, args)
+	}
+	stmt, done, err := c.store.stmts.get(c.db, c.name, query)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return stmt.Query(args...)
+}
+
+// querySelectRow executes select statement made by query returning single row
+// with cached prepared statement.
+func (c *collection) querySelectRow(query string, args ...interface{}) (*sql.Row, error) {
+	var err = c.init()
+	if err != nil {
+		return nil, err
+	}
+	if debug.Enabled() {
+		debug.Debug("%s; args: %v", query, args)
+	}
+	stmt, done, err := c.store.stmts.get(c.db, c.name, query)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return stmt.QueryRow(args...), nil
+}
+
 // Name of collection.
 func (c *collection) Name() string {
 	return c.name
 }
 
 func (c *collection) QueryCount(query *query) (int64, error) {
-	var stmt, args = query.makeSelectStmt("count(*) as count")
-	var row, err = c.QueryRow(stmt, args...)
+	var stmt, args, err = query.makeSelectStmt(true)
+	if err != nil {
+		return 0, err
+	}
+	row, err := c.querySelectRow(stmt, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -113,33 +186,46 @@ func (c *collection) insertOne(doc interface{}) error {
 	var meta = reflection.GetMeta(doc)
 	meta.SetCreatedAt(doc, now)
 	meta.SetUpdatedAt(doc, now)
-	var b, err = json.Marshal(doc)
+	id, err := c.store.NewID(c.name, doc)
 	if err != nil {
 		return err
 	}
-	var cmd = fmt.Sprintf("INSERT INTO %s (data) VALUES ('%s') RETURNING id", c.name, string(b))
-	row, err := c.QueryRow(cmd)
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if len(id) > 0 {
+		_, err = c.Exec(insertInto(c.name, []string{"id", "data"}, "$1", "$2").String(), id, string(b))
+		if err != nil {
+			return err
+		}
+		meta.SetID(doc, id)
+		return nil
+	}
+	var cmd = insertInto(c.name, []string{"data"}, "$1").returning("id")
+	row, err := c.QueryRow(cmd.String(), string(b))
 	if err != nil {
 		return err
 	}
-	var id int64
 	err = row.Scan(&id)
 	if err != nil {
 		return err
 	}
-	var sid = strconv.FormatInt(id, 10)
-	meta.SetID(doc, sid)
+	meta.SetID(doc, id)
 	return nil
 }
 
 // Finds one result.
 func (c *collection) FindOne(result interface{}, query *query) error {
-	var stmt, args = query.makeSelectStmt("")
-	var row, err = c.QueryRow(stmt, args...)
+	var stmt, args, err = query.makeSelectStmt(false)
 	if err != nil {
 		return err
 	}
-	var id int64
+	row, err := c.querySelectRow(stmt, args...)
+	if err != nil {
+		return err
+	}
+	var id string
 	var data []byte
 	err = row.Scan(&id, &data)
 	if err != nil {
@@ -150,8 +236,7 @@ func (c *collection) FindOne(result interface{}, query *query) error {
 		return err
 	}
 	var meta = reflection.GetMeta(result)
-	var sid = strconv.FormatInt(id, 10)
-	meta.SetID(result, sid)
+	meta.SetID(result, id)
 	return nil
 }
 
@@ -162,8 +247,11 @@ func (c *collection) FindAll(result interface{}, query *query) error {
 		return errors.New("result argument must be a slice address")
 	}
 
-	var stmt, args = query.makeSelectStmt("")
-	var rows, err = c.Query(stmt, args...)
+	stmt, args, err := query.makeSelectStmt(false)
+	if err != nil {
+		return err
+	}
+	rows, err := c.querySelect(stmt, args...)
 	if err != nil {
 		return err
 	}
@@ -177,7 +265,7 @@ func (c *collection) FindAll(result interface{}, query *query) error {
 
 	for ; rows.Next(); i++ {
 
-		var id int64
+		var id string
 		var data []byte
 		err = rows.Scan(&id, &data)
 		if err != nil {
@@ -199,8 +287,7 @@ func (c *collection) FindAll(result interface{}, query *query) error {
 			return err
 		}
 
-		var sid = strconv.FormatInt(id, 10)
-		meta.SetID(item, sid)
+		meta.SetID(item, id)
 
 		if isnew {
 			slice = reflect.Append(slice, reflect.ValueOf(item).Elem())
@@ -242,34 +329,54 @@ func (c *collection) Update(selector interface{}, doc interface{}) error {
 	var meta = reflection.GetMeta(doc)
 	meta.SetUpdatedAt(doc, time.Now().UTC())
 	// commit to data store
-	var json = string(b)
-	if id := parseInt(selector); id != nil {
-		_, err = c.Exec(fmt.Sprintf("UPDATE %s SET data=$1 WHERE id=$2", c.name), json, id)
+	if id := c.idParam(selector); id != nil {
+		_, err = c.Exec(update(c.name, "data = $1").where("id = $2").String(), string(b), id)
 		return err
 	}
-	var filter, args = makeFilter([]interface{}{selector})
-	args = append([]interface{}{json}, args...)
-	_, err = c.Exec(fmt.Sprintf("UPDATE %s SET data=$1 WHERE %s", c.name, filter), args...)
+	// parameters of filter follow document
+	var fb = c.filterBuilder()
+	var stmt = update(c.name, "data = "+fb.param(string(b)))
+	stmt.where(fb.build([]interface{}{selector}))
+	if fb.err != nil {
+		return fb.err
+	}
+	_, err = c.Exec(stmt.String(), fb.params...)
 	return err
 }
 
 // Delete documents that match given filter.
 func (c *collection) Delete(selector interface{}) error {
-	if id := parseInt(selector); id != nil {
-		var _, err = c.Exec(fmt.Sprintf("DELETE FROM %s WHERE id=$1", c.name), id)
+	if id := c.idParam(selector); id != nil {
+		var _, err = c.Exec(deleteFrom(c.name).where("id = $1").String(), id)
 		return err
 	}
-	var (
-		args  []interface{}
-		query string
-		cond  string
-	)
-	if selector == nil {
-		query = fmt.Sprintf("DELETE FROM %s", c.name)
-	} else {
-		cond, args = makeFilter([]interface{}{selector})
-		query = fmt.Sprintf("DELETE FROM %s WHERE %s", c.name, cond)
-	}
-	_, err := c.Exec(query, args...)
+	var stmt = deleteFrom(c.name)
+	var b = c.filterBuilder()
+	if selector != nil {
+		stmt.where(b.build([]interface{}{selector}))
+		if b.err != nil {
+			return b.err
+		}
+	}
+	_, err := c.Exec(stmt.String(), b.params...)
 	return err
 }
+
+// filterBuilder makes builder of filters on collection.
+func (c *collection) filterBuilder() *filterBuilder {
+	return &filterBuilder{
+		textID:     c.textID,
+		textFields: c.store.textFields(c.name),
+	}
+}
+
+// idParam returns id parameter if given selector is id.
+func (c *collection) idParam(selector interface{}) interface{} {
+	if c.textID {
+		if id, ok := selector.(string); ok {
+			return id
+		}
+		return nil
+	}
+	return parseInt(selector)
+}
@@ -3,7 +3,6 @@ package postgresql
 import (
 	"database/sql"
 	"encoding/json"
-	"strconv"
 
 	"github.com/gocontrib/nosql/reflection"
 )
@@ -21,7 +20,7 @@ func (c *cursor) Next(result interface{}) (bool, error) {
 	if !c.rows.Next() {
 		return false, c.rows.Err()
 	}
-	var id int64
+	var id string
 	var data []byte
 	var err = c.rows.Scan(&id, &data)
 	if err != nil {
@@ -32,7 +31,6 @@ func (c *cursor) Next(result interface{}) (bool, error) {
 		return false, err
 	}
 	var meta = reflection.GetMeta(result)
-	var sid = strconv.FormatInt(id, 10)
-	meta.SetID(result, sid)
+	meta.SetID(result, id)
 	return true, nil
 }
@@ -1,35 +1,51 @@
 package postgresql
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/gocontrib/nosql"
 	"github.com/gocontrib/nosql/q"
 )
 
-func makeFilter(filter []interface{}) (string, []interface{}) {
-	var q = &filterBuilder{}
-	var s = q.build(filter)
-	return s, q.params
-}
-
 type filterBuilder struct {
 	params []interface{}
+	// whether id column holds text ids
+	textID bool
+	// text fields of collection
+	textFields []string
+	// nesting level of array element conditions
+	elem int
+	// nesting level of optional conditions (under Or or Not)
+	optional int
+	// default order of results by required text search and near conditions
+	order []string
+	err   error
 }
 
+// build makes condition of canonical filter (see q.Normalize), contradictions match no rows.
 func (b *filterBuilder) build(filter []interface{}) string {
-	var conds []string
-	for _, v := range filter {
-		conds = append(conds, b.condition(v))
+	var f, ok = q.Normalize(q.And(filter))
+	if !ok {
+		return "false"
+	}
+	if f == nil {
+		return ""
 	}
-	return strings.Join(conds, " and ")
+	return b.condition(f)
 }
 
 func (b *filterBuilder) condition(c interface{}) string {
 	switch t := c.(type) {
 	case q.Not:
-		return fmt.Sprintf("not(%s)", b.condition(t.Condition))
+		// negation matches null values and missing fields
+		b.optional++
+		defer func() { b.optional-- }()
+		return fmt.Sprintf("(%s) IS NOT TRUE", b.condition(t.Condition))
 	case q.And:
 		var conds []string
 		for _, v := range t {
@@ -41,6 +57,8 @@ func (b *filterBuilder) condition(c interface{}) string {
 		}
 		return strings.Join(conds, " and ")
 	case q.Or:
+		b.optional++
+		defer func() { b.optional-- }()
 		var conds []string
 		for _, v := range t {
 			var cond = b.condition(v)
@@ -49,11 +67,24 @@ func (b *filterBuilder) condition(c interface{}) string {
 			}
 			conds = append(conds, cond)
 		}
-		return strings.Join(conds, " or ")
+		if len(conds) == 0 {
+			return ""
+		}
+		return "(" + strings.Join(conds, " or ") + ")"
+	case q.TextSearch:
+		return b.textSearch(t)
+	case vectorMatch:
+		return b.vectorMatch(t)
 	case q.M:
+		// fields are sorted to make same statement of same shape of filter (see stmtCache)
+		var keys []string
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
 		var conds []string
-		for k, v := range t {
-			var cond = b.field(k, v)
+		for _, k := range keys {
+			var cond = b.field(k, t[k])
 			if len(cond) == 0 {
 				continue
 			}
@@ -61,44 +92,337 @@ func (b *filterBuilder) condition(c interface{}) string {
 		}
 		return strings.Join(conds, " and ")
 	default:
-		panic("invalid query")
+		b.err = fmt.Errorf("invalid condition %v", c)
+		return "false"
 	}
 }
 
 func (b *filterBuilder) field(name string, value interface{}) string {
-	if name == "id" || name == "_id" {
-		var val = b.mapInt(value)
-		if val == nil {
-			return ""
+	if (name == "id" || name == "_id") && b.elem == 0 {
+		if !b.textID {
+			var val = b.mapInt(value)
+			if val == nil {
+				return ""
+			}
+			value = val
 		}
-		value = val
+		return b.value("id", "", value)
 	}
-	var field = pgMapField(name)
+	if b.elem == 0 {
+		if cond, ok := b.contains(name, value); ok {
+			return cond
+		}
+	}
+	var root = "data"
+	if b.elem > 0 {
+		root = elemAlias(b.elem) + ".value"
+	}
+	return b.value(pgTextField(root, name), pgJSONField(root, name), value)
+}
+
+// contains makes condition of equality, In, NotIn or NotEqual of document field as containment
+// of documents with field equal to value or array field with value, so GIN index of documents is used.
+// Only scalar values are matched by containment, arrays and objects contained in field
+// are not equal to it.
+func (b *filterBuilder) contains(name string, value interface{}) (string, bool) {
 	switch t := value.(type) {
 	case q.In:
-		var values []string
 		for _, v := range t {
-			values = append(values, b.param(v))
+			if v != nil && !isScalar(v) {
+				return "", false
+			}
+		}
+		var conds []string
+		var null = false
+		for _, v := range t {
+			if v == nil {
+				null = true
+				continue
+			}
+			conds = append(conds, b.containment(name, v))
+		}
+		if null {
+			conds = append(conds, fmt.Sprintf("%s IS NULL", pgTextField("data", name)))
+		}
+		if len(conds) == 0 {
+			return "false", true
 		}
-		return fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ","))
+		return "(" + strings.Join(conds, " OR ") + ")", true
 	case q.NotIn:
+		var cond, _ = b.contains(name, q.In(t))
+		return fmt.Sprintf("%s IS NOT TRUE", cond), true
+	case q.Op:
+		if t.Kind != q.OpNE {
+			return "", false
+		}
+		if cond, ok := b.contains(name, t.Value); ok {
+			return fmt.Sprintf("%s IS NOT TRUE", cond), true
+		}
+		return "", false
+	case q.All, q.Size, q.ElemMatch, q.GeoNear, q.GeoWithin:
+		return "", false
+	}
+	if !isScalar(value) {
+		return "", false
+	}
+	return b.containment(name, value), true
+}
+
+// isComposite determines whether value is JSON array or object.
+func isComposite(value interface{}) bool {
+	data, err := json.Marshal(value)
+	return err == nil && len(data) > 0 && (data[0] == '[' || data[0] == '{')
+}
+
+// jsonEqual makes condition of jsonb field equal to array or object or array field with element
+// equal to it, containment would match supersets of value.
+func (b *filterBuilder) jsonEqual(doc string, value interface{}) string {
+	var p = b.jsonParam(value)
+	return fmt.Sprintf("(%s = %s::jsonb OR %s::jsonb IN (SELECT jsonb_array_elements(CASE WHEN jsonb_typeof(%s) = 'array' THEN %s ELSE '[]'::jsonb END)))",
+		doc, p, p, doc, doc)
+}
+
+// isScalar determines whether value is string, number or boolean.
+func isScalar(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// containment makes condition of field equal to value or array field with value.
+func (b *filterBuilder) containment(name string, value interface{}) string {
+	return fmt.Sprintf("(data @> %s::jsonb OR data @> %s::jsonb)",
+		b.jsonParam(pgDoc(name, value)), b.jsonParam(pgDoc(name, []interface{}{value})))
+}
+
+// pgDoc returns document with field of given path set to value.
+func pgDoc(name string, value interface{}) map[string]interface{} {
+	var path = strings.Split(name, ".")
+	var doc = map[string]interface{}{path[len(path)-1]: value}
+	for i := len(path) - 2; i >= 0; i-- {
+		doc = map[string]interface{}{path[i]: doc}
+	}
+	return doc
+}
+
+// value makes condition of field with given text and jsonb expressions.
+// Conditions on arrays are satisfied by any of array elements as in mongo.
+// SQL NULL stands for null values and missing fields, negations match them (see package q).
+func (b *filterBuilder) value(field, doc string, value interface{}) string {
+	switch t := value.(type) {
+	case q.In:
 		var values []string
+		var docs []string
+		var null = false
+		var conds []string
 		for _, v := range t {
+			if v == nil {
+				null = true
+				continue
+			}
+			if len(doc) > 0 && isComposite(v) {
+				conds = append(conds, b.jsonEqual(doc, v))
+				continue
+			}
 			values = append(values, b.param(v))
+			if len(doc) > 0 {
+				docs = append(docs, b.jsonParam(v)+"::jsonb")
+			}
+		}
+		if len(values) > 0 {
+			conds = append(conds, fmt.Sprintf("%s IN (%s)", field, strings.Join(values, ",")))
+		}
+		if len(docs) > 0 {
+			conds = append(conds, fmt.Sprintf("%s @> ANY(ARRAY[%s])", doc, strings.Join(docs, ",")))
+		}
+		if null {
+			conds = append(conds, fmt.Sprintf("%s IS NULL", field))
+		}
+		if len(conds) == 0 {
+			return "false"
+		}
+		return "(" + strings.Join(conds, " OR ") + ")"
+	case q.NotIn:
+		return fmt.Sprintf("%s IS NOT TRUE", b.value(field, doc, q.In(t)))
+	case q.All:
+		return fmt.Sprintf("%s @> %s::jsonb", doc, b.jsonParam([]interface{}(t)))
+	case q.Size:
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'array' THEN jsonb_array_length(%s) END) = %s", doc, doc, b.param(int(t)))
+	case q.ElemMatch:
+		return b.elemMatch(doc, t.Condition)
+	case q.GeoNear:
+		var lng, lat = pgCoord(doc, 0), pgCoord(doc, 1)
+		var dist = pgDistance(lng, lat, b.param(t.Lng)+"::float8", b.param(t.Lat)+"::float8")
+		if b.optional == 0 && b.elem == 0 {
+			b.order = append(b.order, dist)
+		}
+		if t.MaxDistance <= 0 {
+			return fmt.Sprintf("%s IS NOT NULL", dist)
+		}
+		return fmt.Sprintf("%s <= %s", dist, b.param(t.MaxDistance))
+	case q.GeoWithin:
+		var lng, lat = pgCoord(doc, 0), pgCoord(doc, 1)
+		switch s := t.Shape.(type) {
+		case q.Box:
+			return fmt.Sprintf("(%s BETWEEN %s AND %s AND %s BETWEEN %s AND %s)",
+				lng, b.param(s.MinLng), b.param(s.MaxLng), lat, b.param(s.MinLat), b.param(s.MaxLat))
+		case q.Polygon:
+			var points []string
+			for _, p := range s {
+				points = append(points, fmt.Sprintf("(%v,%v)", p[0], p[1]))
+			}
+			return fmt.Sprintf("point(%s, %s) <@ %s::polygon", lng, lat, b.param("("+strings.Join(points, ",")+")"))
+		default:
+			panic("invalid query")
 		}
-		return fmt.Sprintf("%s NOT IN (%s)", field, strings.Join(values, ","))
 	case q.Op:
-		return fmt.Sprintf("%s %s %s", field, sqlop(t.Kind), b.param(t.Value))
+		switch t.Kind {
+		case q.OpRegex:
+			var re, _ = t.Value.(q.RegexValue)
+			var op = "~"
+			if strings.Contains(re.Flags, "i") {
+				op = "~*"
+			}
+			return fmt.Sprintf("%s %s %s", field, op, b.param(pgRegex(re)))
+		case q.OpPrefix:
+			return fmt.Sprintf("%s LIKE %s", field, b.param(escapeLike(fmt.Sprint(t.Value))+"%"))
+		case q.OpIEqual:
+			return fmt.Sprintf("lower(%s) = lower(%s)", field, b.param(t.Value))
+		case q.OpExists:
+			if len(doc) == 0 {
+				doc = field
+			}
+			if exists, _ := t.Value.(bool); exists {
+				return fmt.Sprintf("%s IS NOT NULL", doc)
+			}
+			return fmt.Sprintf("%s IS NULL", doc)
+		case q.OpNE:
+			return fmt.Sprintf("%s IS NOT TRUE", b.value(field, doc, t.Value))
+		}
+		return b.compare(field, doc, sqlop(t.Kind), t.Value)
+	default:
+		if value == nil {
+			return fmt.Sprintf("(%s IS NULL)", field)
+		}
+		if len(doc) == 0 {
+			return fmt.Sprintf("%s = %s", field, b.param(value))
+		}
+		if isComposite(value) {
+			return b.jsonEqual(doc, value)
+		}
+		return fmt.Sprintf("(%s = %s OR %s @> %s::jsonb)", field, b.param(value), doc, b.jsonParam(value))
+	}
+}
+
+// textSearch makes condition of full-text search over text fields of collection.
+func (b *filterBuilder) textSearch(t q.TextSearch) string {
+	if len(b.textFields) == 0 {
+		b.err = data.ErrNoTextIndex
+		return "false"
+	}
+	var query string
+	if t.All {
+		query = fmt.Sprintf("plainto_tsquery('%s', %s)", data.TextLanguage, b.param(t.Search))
+	} else {
+		var list []string
+		for _, s := range strings.Fields(t.Search) {
+			list = append(list, fmt.Sprintf("plainto_tsquery('%s', %s)", data.TextLanguage, b.param(s)))
+		}
+		if len(list) == 0 {
+			return "false"
+		}
+		query = "(" + strings.Join(list, " || ") + ")"
+	}
+	var vector = pgTextVector(b.textFields)
+	if b.optional == 0 && b.elem == 0 {
+		b.order = append(b.order, fmt.Sprintf("ts_rank(%s, %s) DESC", vector, query))
+	}
+	return fmt.Sprintf("%s @@ %s", vector, query)
+}
+
+// pgTextVector returns tsvector expression of text fields, it matches expression of text index.
+func pgTextVector(fields []string) string {
+	var list []string
+	for _, f := range fields {
+		list = append(list, fmt.Sprintf("coalesce(%s, '')", pgTextField("data", f)))
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", data.TextLanguage, strings.Join(list, " || ' ' || "))
+}
+
+// pgCoord returns coordinate of GeoJSON point with given jsonb expression.
+func pgCoord(doc string, i int) string {
+	return fmt.Sprintf("(%s->'coordinates'->>%d)::float8", doc, i)
+}
+
+// pgDistance returns haversine distance in meters between points.
+func pgDistance(lng1, lat1, lng2, lat2 string) string {
+	return fmt.Sprintf("(2 * %.1f * asin(least(1, sqrt(power(sin(radians(%s - %s) / 2), 2) + cos(radians(%s)) * cos(radians(%s)) * power(sin(radians(%s - %s) / 2), 2)))))",
+		data.EarthRadius, lat2, lat1, lat1, lat2, lng2, lng1)
+}
+
+// elemMatch makes condition matching arrays with element satisfying given condition.
+func (b *filterBuilder) elemMatch(doc string, c interface{}) string {
+	b.elem++
+	var alias = elemAlias(b.elem)
+	var cond = b.elemCondition(alias+".value", c)
+	b.elem--
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM jsonb_array_elements(CASE WHEN jsonb_typeof(%s) = 'array' THEN %s ELSE '[]'::jsonb END) AS %s(value) WHERE %s)",
+		doc, doc, alias, cond)
+}
+
+// elemCondition makes condition of array element with given jsonb expression.
+func (b *filterBuilder) elemCondition(elem string, c interface{}) string {
+	switch t := c.(type) {
+	case q.Not:
+		return fmt.Sprintf("(%s) IS NOT TRUE", b.elemCondition(elem, t.Condition))
+	case q.And:
+		var conds []string
+		for _, v := range t {
+			conds = append(conds, b.elemCondition(elem, v))
+		}
+		return "(" + strings.Join(conds, " and ") + ")"
+	case q.Or:
+		var conds []string
+		for _, v := range t {
+			conds = append(conds, b.elemCondition(elem, v))
+		}
+		return "(" + strings.Join(conds, " or ") + ")"
+	case q.M:
+		return "(" + b.condition(t) + ")"
 	default:
-		return fmt.Sprintf("%s = %s", field, b.param(value))
+		// condition on element itself
+		return b.value(elem+" #>> '{}'", elem, c)
 	}
 }
 
-func pgMapField(name string) string {
-	if name == "id" || name == "_id" {
-		return "id"
+// elemAlias returns alias of array elements at given nesting level.
+func elemAlias(level int) string {
+	return fmt.Sprintf("e%d", level)
+}
+
+// pgTextField returns text expression of field of given jsonb document.
+func pgTextField(root, name string) string {
+	if strings.Contains(name, ".") {
+		// nested field path
+		return fmt.Sprintf("%s #>> %s", root, pgPath(name))
+	}
+	return fmt.Sprintf("%s->>%s", root, pgLiteral(name))
+}
+
+// pgJSONField returns jsonb expression of field of given jsonb document.
+func pgJSONField(root, name string) string {
+	if strings.Contains(name, ".") {
+		return fmt.Sprintf("%s #> %s", root, pgPath(name))
 	}
-	return fmt.Sprintf("data->>'%s'", name)
+	return fmt.Sprintf("%s->%s", root, pgLiteral(name))
 }
 
 func (b *filterBuilder) mapInt(value interface{}) interface{} {
@@ -124,11 +448,11 @@ func (b *filterBuilder) mapInt(value interface{}) interface{} {
 		}
 		return values
 	case q.Op:
-		var val = parseInt(value)
+		var val = parseInt(t.Value)
 		if val == nil {
 			return nil
 		}
-		return q.Op{t.Kind, val}
+		return q.Op{Kind: t.Kind, Value: val}
 	default:
 		var val = parseInt(value)
 		if val == nil {
@@ -161,6 +485,39 @@ func (b *filterBuilder) param(val interface{}) string {
 	return fmt.Sprintf("%s%d", "$", len(b.params))
 }
 
+// jsonParam adds JSON representation of given value as parameter.
+func (b *filterBuilder) jsonParam(val interface{}) string {
+	data, err := json.Marshal(val)
+	if err != nil {
+		panic("invalid query value")
+	}
+	return b.param(string(data))
+}
+
+// pgRegex returns pattern with embedded options matching semantics of flags in other stores.
+func pgRegex(re q.RegexValue) string {
+	var m = strings.Contains(re.Flags, "m")
+	var s = strings.Contains(re.Flags, "s")
+	switch {
+	case m && s:
+		// ^ and $ match at lines, . matches new line
+		return "(?w)" + re.Pattern
+	case m:
+		return "(?n)" + re.Pattern
+	case s:
+		return "(?s)" + re.Pattern
+	default:
+		// . does not match new line
+		return "(?p)" + re.Pattern
+	}
+}
+
+// escapeLike escapes special characters of LIKE pattern.
+func escapeLike(s string) string {
+	var r = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return r.Replace(s)
+}
+
 // returns equvivalent SQL operator
 func sqlop(v q.OpKind) string {
 	switch v {
@@ -27,42 +27,68 @@ func (q *query) copy() *query {
 	}
 }
 
-// makes select statement with parameters
-func (q *query) makeSelectStmt(cols string) (string, []interface{}) {
-	if len(cols) == 0 {
-		cols = "id, data"
+// makes select statement with parameters, count statement is not ordered.
+// Vector search is resolved with separate query before.
+func (q *query) makeSelectStmt(count bool) (string, []interface{}, error) {
+	var conds, err = q.collection.resolveVector(q.filter)
+	if err != nil {
+		return "", nil, err
+	}
+	var b = q.collection.filterBuilder()
+	var stmt = selectFrom(q.table, "id", "data").where(b.build(conds))
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if !count {
+		stmt.orderBy(q.orderBy(b.order)...)
 	}
-	var limit = ""
 	if q.limit > 0 {
-		limit = fmt.Sprintf(" LIMIT %d", q.limit)
+		stmt.limit(b.param(q.limit))
 	}
-	var offset = ""
 	if q.skip > 0 {
-		offset = fmt.Sprintf(" OFFSET %d", q.skip)
+		stmt.offset(b.param(q.skip))
 	}
-	var orderby = q.orderBy()
-	var filter, args = makeFilter(q.filter)
-	var where = ""
-	if len(filter) > 0 {
-		where = fmt.Sprintf(" WHERE %s", filter)
+	if !count {
+		return stmt.String(), b.params, nil
 	}
-	var query = fmt.Sprintf("SELECT %s FROM %s%s%s%s%s", cols, q.table, limit, offset, orderby, where)
-	return query, args
+	if q.limit > 0 || q.skip > 0 {
+		// count of rows in page
+		stmt.columns = []string{"1"}
+		return fmt.Sprintf("SELECT count(*) FROM (%s) AS page", stmt), b.params, nil
+	}
+	stmt.columns = []string{"count(*)"}
+	return stmt.String(), b.params, nil
 }
 
-func (q *query) orderBy() string {
-	if len(q.sort) == 0 {
-		return ""
+// orderBy returns sort expressions, results of text search are ordered by relevance
+// and results of near and vector search by distance unless sorted explicitly.
+func (q *query) orderBy(order []string) []string {
+	if len(q.sort) == 0 && len(order) > 0 {
+		return append(order, "id")
 	}
 	var list []string
 	for _, f := range q.sort {
+		// nulls and missing fields are the least values as in other stores
 		if strings.HasPrefix(f, "-") {
-			list = append(list, fmt.Sprintf("%s DESC", pgMapField(f[1:])))
+			list = append(list, fmt.Sprintf("%s DESC NULLS LAST", q.sortField(f[1:])))
 		} else {
-			list = append(list, pgMapField(f))
+			list = append(list, fmt.Sprintf("%s NULLS FIRST", q.sortField(f)))
 		}
 	}
-	return fmt.Sprintf(" ORDER BY %s", strings.Join(list, ", "))
+	return list
+}
+
+// sortField returns sort expression of field, it is cast to declared type of field (see DeclareType)
+// or jsonb value of field is sorted by jsonb ordering.
+func (q *query) sortField(name string) string {
+	if name == "id" || name == "_id" {
+		return "id"
+	}
+	var typ = q.collection.store.fieldType(q.collection.name, name)
+	if typ == "" {
+		return pgJSONField("data", name)
+	}
+	return pgTyped(pgTextField("data", name), pgJSONField("data", name), typ)
 }
 
 // Count returns the number of items that match the set conditions.
@@ -103,10 +129,41 @@ func (q *query) Sort(fields ...string) data.Result {
 
 // Cursor executes query and returns cursor capable of going over all the results.
 func (q *query) Cursor() (data.Cursor, error) {
-	var stmt, args = q.makeSelectStmt("")
-	var rows, err = q.collection.Query(stmt, args...)
+	var stmt, args, err = q.makeSelectStmt(false)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.collection.querySelect(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 	return &cursor{q.collection.store, rows}, nil
 }
+
+// Explain returns select statement, its parameters and output of EXPLAIN of the statement.
+// Vector search is resolved with separate query before.
+func (q *query) Explain() (data.Plan, error) {
+	var plan data.Plan
+	var stmt, args, err = q.makeSelectStmt(false)
+	if err != nil {
+		return plan, err
+	}
+	plan.Statement = stmt
+	plan.Args = args
+
+	rows, err := q.collection.Query("EXPLAIN "+stmt, args...)
+	if err != nil {
+		return plan, err
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err = rows.Scan(&line); err != nil {
+			return plan, err
+		}
+		lines = append(lines, line)
+	}
+	plan.Explain = strings.Join(lines, "\n")
+	return plan, rows.Err()
+}
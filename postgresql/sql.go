@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// sqlStmt builds SQL statement, clauses are rendered in order of SQL syntax
+// whatever order they are given in. Values are never embedded into statement,
+// they are positional parameters of filterBuilder (see filterBuilder.param).
+type sqlStmt struct {
+	verb     string
+	table    string
+	columns  []string
+	values   []string
+	set      []string
+	conds    []string
+	order    []string
+	limitTo  string
+	offsetBy string
+	returns  []string
+}
+
+// selectFrom starts SELECT statement of given columns from table.
+func selectFrom(table string, columns ...string) *sqlStmt {
+	return &sqlStmt{verb: "SELECT", table: table, columns: columns}
+}
+
+// insertInto starts INSERT statement of given columns and values to table.
+func insertInto(table string, columns []string, values ...string) *sqlStmt {
+	return &sqlStmt{verb: "INSERT", table: table, columns: columns, values: values}
+}
+
+// update starts UPDATE statement of table with given assignments.
+func update(table string, set ...string) *sqlStmt {
+	return &sqlStmt{verb: "UPDATE", table: table, set: set}
+}
+
+// deleteFrom starts DELETE statement of table.
+func deleteFrom(table string) *sqlStmt {
+	return &sqlStmt{verb: "DELETE", table: table}
+}
+
+// where adds conditions joined with AND, empty conditions are ignored.
+func (s *sqlStmt) where(conds ...string) *sqlStmt {
+	for _, c := range conds {
+		if len(c) > 0 {
+			s.conds = append(s.conds, c)
+		}
+	}
+	return s
+}
+
+// orderBy adds sort expressions.
+func (s *sqlStmt) orderBy(exprs ...string) *sqlStmt {
+	s.order = append(s.order, exprs...)
+	return s
+}
+
+// limit sets parameter of maximum number of rows.
+func (s *sqlStmt) limit(param string) *sqlStmt {
+	s.limitTo = param
+	return s
+}
+
+// offset sets parameter of number of skipped rows.
+func (s *sqlStmt) offset(param string) *sqlStmt {
+	s.offsetBy = param
+	return s
+}
+
+// returning sets columns returned by statement.
+func (s *sqlStmt) returning(columns ...string) *sqlStmt {
+	s.returns = columns
+	return s
+}
+
+func (s *sqlStmt) String() string {
+	var b strings.Builder
+	var table = pgIdent(s.table)
+	switch s.verb {
+	case "SELECT":
+		b.WriteString("SELECT " + strings.Join(s.columns, ", ") + " FROM " + table)
+	case "INSERT":
+		b.WriteString("INSERT INTO " + table + " (" + strings.Join(s.columns, ", ") + ") VALUES (" + strings.Join(s.values, ", ") + ")")
+	case "UPDATE":
+		b.WriteString("UPDATE " + table + " SET " + strings.Join(s.set, ", "))
+	case "DELETE":
+		b.WriteString("DELETE FROM " + table)
+	}
+	if len(s.conds) > 0 {
+		b.WriteString(" WHERE " + strings.Join(s.conds, " and "))
+	}
+	if len(s.order) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(s.order, ", "))
+	}
+	if len(s.limitTo) > 0 {
+		b.WriteString(" LIMIT " + s.limitTo)
+	}
+	if len(s.offsetBy) > 0 {
+		b.WriteString(" OFFSET " + s.offsetBy)
+	}
+	if len(s.returns) > 0 {
+		b.WriteString(" RETURNING " + strings.Join(s.returns, ", "))
+	}
+	return b.String()
+}
+
+// pgIdent returns quoted identifier of table, index or database.
+func pgIdent(name string) string {
+	return pq.QuoteIdentifier(name)
+}
+
+// pgLiteral returns quoted string literal, it is used for names of JSON fields
+// which are parts of expressions of indexes and so cannot be parameters.
+func pgLiteral(s string) string {
+	return pq.QuoteLiteral(s)
+}
+
+// pgPath returns text array literal of path of nested field.
+func pgPath(name string) string {
+	var list = strings.Split(name, ".")
+	for i, s := range list {
+		if len(s) == 0 || strings.ContainsAny(s, " \t\n,{}\"\\") || strings.EqualFold(s, "null") {
+			list[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+	}
+	return pgLiteral("{" + strings.Join(list, ",") + "}")
+}
@@ -3,6 +3,8 @@ package postgresql
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/gocontrib/log"
 	"github.com/gocontrib/nosql"
@@ -35,13 +37,13 @@ func Open(connectionURL string, databaseName string, dropDatabase bool) (data.St
 	defer db.Close()
 
 	if dropDatabase {
-		_, err = db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", databaseName))
+		_, err = db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", pgIdent(databaseName)))
 		if err != nil {
 			debug.Error("unable to drop database: %v", err)
 		}
 	}
 
-	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE %s", databaseName))
+	_, err = db.Exec(fmt.Sprintf("CREATE DATABASE %s", pgIdent(databaseName)))
 	if err != nil {
 		debug.Error("unable to create database: %v", err)
 	}
@@ -58,20 +60,79 @@ func Open(connectionURL string, databaseName string, dropDatabase bool) (data.St
 }
 
 type store struct {
+	data.IDGenerators
 	db   *sql.DB
 	name string
+	mu   sync.Mutex
+	// text fields by collection
+	text map[string][]string
+	// dimensions of indexed vector fields by collection and field
+	vectors map[string]int
+	// whether pgvector extension is installed, nil until checked
+	pgvector *bool
+	// declared types of fields by collection and field
+	types map[string]FieldType
+	// names of created tables
+	tables sync.Map
+	// prepared statements by collection
+	stmts stmtCache
 }
 
 // Collection returns collection by name.
 func (s *store) Collection(name string) data.Collection {
 	return &collection{
-		store: s,
-		db:    s.db,
-		name:  name,
+		store:  s,
+		db:     s.db,
+		name:   name,
+		textID: !s.NativeID(name),
 	}
 }
 
+// NewID generates id of given document. Tables of collections with custom id
+// generators have TEXT id column without default, so empty id is an error.
+func (s *store) NewID(collection string, doc interface{}) (string, error) {
+	id, err := s.IDGenerators.NewID(collection, doc)
+	if err != nil {
+		return "", err
+	}
+	if len(id) == 0 && !s.NativeID(collection) {
+		return "", fmt.Errorf("id generator of collection %s returned empty id", collection)
+	}
+	return id, nil
+}
+
+// TextIndex declares text fields of collection creating GIN index of their tsvector.
+func (s *store) TextIndex(name string, fields ...string) error {
+	s.mu.Lock()
+	if s.text == nil {
+		s.text = make(map[string][]string)
+	}
+	s.text[name] = fields
+	s.mu.Unlock()
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	var idx = strings.Replace("txt_"+name+"_"+strings.Join(fields, "_"), ".", "_", -1)
+	var stmt = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN(%s)", pgIdent(idx), pgIdent(name), pgTextVector(fields))
+	_, err := s.Collection(name).(*collection).Exec(stmt)
+	return err
+}
+
+// GeoIndex does nothing, distances of points are calculated without index.
+func (s *store) GeoIndex(name string, fields ...string) error {
+	return nil
+}
+
+func (s *store) textFields(collection string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.text[collection]
+}
+
 // Close performs cleanups.
 func (s *store) Close() error {
+	s.stmts.close()
 	return s.db.Close()
 }
@@ -0,0 +1,110 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gocontrib/nosql"
+)
+
+// FieldType is SQL type of JSON values of field.
+type FieldType string
+
+const (
+	// Numeric type of JSON numbers.
+	Numeric FieldType = "numeric"
+	// Boolean type of JSON booleans.
+	Boolean FieldType = "boolean"
+	// Timestamp type of JSON strings holding RFC 3339 times.
+	Timestamp FieldType = "timestamptz"
+	// Text type of JSON strings, they are compared byte-wise like in other stores.
+	Text FieldType = "text"
+)
+
+var errNotPgStore = errors.New("store is not postgresql store")
+
+// DeclareType declares SQL type of field of collection used to sort results by the field.
+// Results are sorted by jsonb ordering of fields without declared type.
+// Values of other JSON types are sorted as nulls.
+func DeclareType(ds data.Store, collection, field string, typ FieldType) error {
+	s, ok := ds.(*store)
+	if !ok {
+		return errNotPgStore
+	}
+	switch typ {
+	case Numeric, Boolean, Timestamp, Text:
+	default:
+		return fmt.Errorf("unsupported field type %q", typ)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.types == nil {
+		s.types = make(map[string]FieldType)
+	}
+	s.types[collection+"."+field] = typ
+	return nil
+}
+
+func (s *store) fieldType(collection, field string) FieldType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.types[collection+"."+field]
+}
+
+// pgType returns SQL type of comparison with given value, empty type means jsonb comparison.
+func pgType(v interface{}) FieldType {
+	switch v.(type) {
+	case time.Time:
+		return Timestamp
+	case json.Number:
+		return Numeric
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return Numeric
+	case reflect.Bool:
+		return Boolean
+	case reflect.String:
+		return Text
+	}
+	return ""
+}
+
+// pgTyped returns expression of field with given text and jsonb expressions cast to SQL type,
+// it is NULL for values of other JSON types, so comparisons never match them as in mongo.
+func pgTyped(field, doc string, typ FieldType) string {
+	switch typ {
+	case Numeric:
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'number' THEN (%s)::numeric END)", doc, field)
+	case Boolean:
+		return fmt.Sprintf("(CASE WHEN jsonb_typeof(%s) = 'boolean' THEN (%s)::boolean END)", doc, field)
+	case Timestamp:
+		return fmt.Sprintf(`(CASE WHEN jsonb_typeof(%s) = 'string' AND %s ~ '^\d{4}-\d\d-\d\dT\d\d:\d\d' THEN (%s)::timestamptz END)`,
+			doc, field, field)
+	case Text:
+		return fmt.Sprintf(`(CASE WHEN jsonb_typeof(%s) = 'string' THEN %s END) COLLATE "C"`, doc, field)
+	}
+	return doc
+}
+
+// compare makes comparison of field with given text and jsonb expressions with value.
+// Type of comparison is chosen by Go type of value, other values are compared by jsonb ordering.
+func (b *filterBuilder) compare(field, doc, op string, value interface{}) string {
+	if len(doc) == 0 {
+		// id column
+		return fmt.Sprintf("%s %s %s", field, op, b.param(value))
+	}
+	var typ = pgType(value)
+	if typ == "" {
+		return fmt.Sprintf("%s %s %s::jsonb", doc, op, b.jsonParam(value))
+	}
+	if n, ok := value.(json.Number); ok {
+		value = n.String()
+	}
+	return fmt.Sprintf("%s %s %s::%s", pgTyped(field, doc, typ), op, b.param(value), typ)
+}
@@ -0,0 +1,263 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/q"
+	"github.com/lib/pq"
+)
+
+// vectorMatch is resolved vector search, it holds ids of found documents in order of distance.
+type vectorMatch struct {
+	ids []string
+}
+
+// VectorIndex installs pgvector extension and creates HNSW index of vector field.
+// Without pgvector nearest vectors are found by exact search on client side.
+func (s *store) VectorIndex(name, field string, dims int, metric q.VectorMetric) error {
+	var c = s.Collection(name).(*collection)
+	_, err := c.Exec("CREATE EXTENSION IF NOT EXISTS vector")
+	if err != nil {
+		debug.Error("pgvector is not available: %v", err)
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.vectors == nil {
+		s.vectors = make(map[string]int)
+	}
+	s.vectors[name+"."+field] = dims
+	s.pgvector = nil
+	s.mu.Unlock()
+
+	var idx = strings.Replace("vec_"+name+"_"+field, ".", "_", -1)
+	var stmt = fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw ((%s) %s)",
+		pgIdent(idx), pgIdent(name), pgVector(pgJSONField("data", field), dims), pgVectorOps(metric))
+	_, err = c.Exec(stmt)
+	return err
+}
+
+// hasPgvector determines whether pgvector extension is installed.
+func (s *store) hasPgvector(c *collection) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pgvector != nil {
+		return *s.pgvector, nil
+	}
+	row, err := c.QueryRow("SELECT count(*) FROM pg_extension WHERE extname = 'vector'")
+	if err != nil {
+		return false, err
+	}
+	var n int
+	err = row.Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	var ok = n > 0
+	s.pgvector = &ok
+	return ok, nil
+}
+
+// vectorDims returns number of dimensions of indexed vector field, zero if field is not indexed.
+func (s *store) vectorDims(collection, field string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vectors[collection+"."+field]
+}
+
+// resolveVector replaces top-level vector search of filter with ids of found documents.
+func (c *collection) resolveVector(filter []interface{}) ([]interface{}, error) {
+	var vs, rest = splitVector(filter)
+	if vs == nil {
+		return filter, nil
+	}
+	ok, err := c.store.hasPgvector(c)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if ok {
+		ids, err = c.nearestVectors(*vs, rest)
+	} else {
+		ids, err = c.scanVectors(*vs, rest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]interface{}{vectorMatch{ids}}, rest...), nil
+}
+
+// nearestVectors finds nearest vectors with pgvector operators.
+func (c *collection) nearestVectors(vs q.VectorSearch, filter []interface{}) ([]string, error) {
+	var b = c.filterBuilder()
+	var doc = pgJSONField("data", vs.Field)
+	var conds = []string{fmt.Sprintf("jsonb_typeof(%s) = 'array'", doc)}
+	if cond := b.build(filter); len(cond) > 0 {
+		conds = append(conds, cond)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	var dist = fmt.Sprintf("%s %s %s::vector", pgVector(doc, c.store.vectorDims(c.name, vs.Field)),
+		pgVectorOp(vs.Metric), b.param(pgVectorValue(vs.Vector)))
+	var stmt = selectFrom(c.name, "id").where(conds...).orderBy(dist, "id")
+	if vs.K > 0 {
+		stmt.limit(b.param(vs.K))
+	}
+	rows, err := c.Query(stmt.String(), b.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids = []string{}
+	for rows.Next() {
+		var id string
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// scanVectors finds nearest vectors by exact search on client side.
+func (c *collection) scanVectors(vs q.VectorSearch, filter []interface{}) ([]string, error) {
+	var b = c.filterBuilder()
+	var doc = pgJSONField("data", vs.Field)
+	var conds = []string{fmt.Sprintf("jsonb_typeof(%s) = 'array'", doc)}
+	if cond := b.build(filter); len(cond) > 0 {
+		conds = append(conds, cond)
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+	var stmt = selectFrom(c.name, "id", doc).where(conds...).orderBy("id")
+	rows, err := c.Query(stmt.String(), b.params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type cand struct {
+		id string
+		d  float64
+	}
+	var list []cand
+	for rows.Next() {
+		var id string
+		var v []byte
+		err = rows.Scan(&id, &v)
+		if err != nil {
+			return nil, err
+		}
+		var vec []float32
+		if json.Unmarshal(v, &vec) != nil || len(vec) == 0 {
+			// not a vector of numbers
+			continue
+		}
+		list = append(list, cand{id, data.VectorDistance(vs.Metric, vs.Vector, vec)})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].d < list[j].d
+	})
+	if vs.K > 0 && len(list) > vs.K {
+		list = list[:vs.K]
+	}
+	var ids = make([]string, len(list))
+	for i, e := range list {
+		ids[i] = e.id
+	}
+	return ids, nil
+}
+
+// vectorMatch makes condition matching found documents, they are ordered by distance.
+func (b *filterBuilder) vectorMatch(m vectorMatch) string {
+	if len(m.ids) == 0 {
+		return "false"
+	}
+	var ids = b.param(pq.Array(m.ids))
+	if b.optional == 0 && b.elem == 0 {
+		b.order = append(b.order, fmt.Sprintf("array_position(%s, id)", ids))
+	}
+	return fmt.Sprintf("id = ANY(%s)", ids)
+}
+
+// splitVector returns vector search condition of filter and other conditions.
+// Vector search is top-level condition, it is allowed in top-level And too.
+func splitVector(filter []interface{}) (*q.VectorSearch, []interface{}) {
+	var vs *q.VectorSearch
+	var rest []interface{}
+	for _, c := range filter {
+		switch t := c.(type) {
+		case q.VectorSearch:
+			if vs != nil {
+				panic("invalid query")
+			}
+			vs = &t
+			continue
+		case q.And:
+			if v, r := splitVector(t); v != nil {
+				if vs != nil {
+					panic("invalid query")
+				}
+				vs = v
+				rest = append(rest, r...)
+				continue
+			}
+		}
+		rest = append(rest, c)
+	}
+	return vs, rest
+}
+
+// pgVector returns pgvector expression of jsonb array, it matches expression of vector index.
+func pgVector(doc string, dims int) string {
+	if dims > 0 {
+		return fmt.Sprintf("((%s)::text)::vector(%d)", doc, dims)
+	}
+	return fmt.Sprintf("((%s)::text)::vector", doc)
+}
+
+// pgVectorValue formats vector as pgvector literal.
+func pgVectorValue(vec []float32) string {
+	var list = make([]string, len(vec))
+	for i, v := range vec {
+		list[i] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	return "[" + strings.Join(list, ",") + "]"
+}
+
+// pgVectorOp returns pgvector distance operator of metric.
+func pgVectorOp(metric q.VectorMetric) string {
+	switch metric {
+	case q.Euclidean:
+		return "<->"
+	case q.DotProduct:
+		return "<#>"
+	default:
+		return "<=>"
+	}
+}
+
+// pgVectorOps returns pgvector operator class of metric.
+func pgVectorOps(metric q.VectorMetric) string {
+	switch metric {
+	case q.Euclidean:
+		return "vector_l2_ops"
+	case q.DotProduct:
+		return "vector_ip_ops"
+	default:
+		return "vector_cosine_ops"
+	}
+}
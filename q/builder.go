@@ -0,0 +1,413 @@
+package q
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	errEmptyCond = errors.New("q: empty condition")
+	errElemCond  = errors.New("q: condition on array element is used outside of ElemMatch")
+)
+
+// Cond is condition made by builder, e.g.
+//
+//	q.Field("age").Gte(20).And(q.Field("name").In("bob", "rob"))
+//
+// Conditions are validated while they are built, the first error is kept in
+// condition and returned by Build, so conditions are chained without error checks.
+type Cond struct {
+	cond interface{}
+	err  error
+	// whether condition is made on array element itself (see Elem)
+	elem bool
+}
+
+// FieldRef makes conditions on field.
+type FieldRef struct {
+	name string
+	err  error
+}
+
+// Field starts condition on given field, nested fields are separated by dots.
+func Field(name string) FieldRef {
+	var f = FieldRef{name: name}
+	if len(name) == 0 || strings.HasPrefix(name, "$") || strings.HasPrefix(name, ".") ||
+		strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		f.err = fmt.Errorf("q: invalid field name %q", name)
+	}
+	return f
+}
+
+// Elem starts condition on array element itself for ElemMatch, e.g.
+//
+//	q.Field("scores").ElemMatch(q.Elem().Gte(5).And(q.Elem().Lt(10)))
+func Elem() FieldRef {
+	return FieldRef{}
+}
+
+// Where wraps condition of query model (e.g. Text) to combine it with built conditions.
+func Where(filter interface{}) Cond {
+	if filter == nil {
+		return Cond{err: errEmptyCond}
+	}
+	if m, ok := filter.(M); ok && len(m) == 0 {
+		return Cond{err: errEmptyCond}
+	}
+	return Cond{cond: filter}
+}
+
+// AllOf joins given conditions as logical conjunction.
+func AllOf(conds ...Cond) Cond {
+	return combine(And{}, conds)
+}
+
+// AnyOf joins given conditions as logical disjunction.
+func AnyOf(conds ...Cond) Cond {
+	return combine(Or{}, conds)
+}
+
+// Build returns condition of query model or the first error of building.
+func (c Cond) Build() (interface{}, error) {
+	if c.err == nil && c.elem {
+		return nil, errElemCond
+	}
+	return c.build()
+}
+
+func (c Cond) build() (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.cond == nil {
+		return nil, errEmptyCond
+	}
+	return c.cond, nil
+}
+
+// MustBuild returns condition of query model, it panics on error of building.
+func (c Cond) MustBuild() interface{} {
+	var f, err = c.Build()
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Err returns the first error of building.
+func (c Cond) Err() error {
+	return c.err
+}
+
+// And joins condition with given conditions as logical conjunction.
+func (c Cond) And(conds ...Cond) Cond {
+	return combine(And{}, append([]Cond{c}, conds...))
+}
+
+// Or joins condition with given conditions as logical disjunction.
+func (c Cond) Or(conds ...Cond) Cond {
+	return combine(Or{}, append([]Cond{c}, conds...))
+}
+
+// Not negates condition, double negation is removed.
+func (c Cond) Not() Cond {
+	if c.err != nil {
+		return c
+	}
+	if c.cond == nil {
+		return Cond{err: errEmptyCond}
+	}
+	if n, ok := c.cond.(Not); ok {
+		return Cond{cond: n.Condition, elem: c.elem}
+	}
+	return Cond{cond: Not{c.cond}, elem: c.elem}
+}
+
+// combine joins conditions to And or Or given as kind, nested conditions of the same kind are flattened.
+func combine(kind interface{}, conds []Cond) Cond {
+	if len(conds) == 0 {
+		return Cond{err: errEmptyCond}
+	}
+	var list []interface{}
+	var elem bool
+	for _, c := range conds {
+		if c.err != nil {
+			return c
+		}
+		elem = elem || c.elem
+		if c.cond == nil {
+			return Cond{err: errEmptyCond}
+		}
+		switch t := c.cond.(type) {
+		case And:
+			if _, ok := kind.(And); ok {
+				list = append(list, t...)
+				continue
+			}
+		case Or:
+			if _, ok := kind.(Or); ok {
+				list = append(list, t...)
+				continue
+			}
+		}
+		list = append(list, c.cond)
+	}
+	if len(list) == 1 {
+		return Cond{cond: list[0], elem: elem}
+	}
+	if _, ok := kind.(Or); ok {
+		return Cond{cond: Or(list), elem: elem}
+	}
+	return Cond{cond: And(list), elem: elem}
+}
+
+// cond makes condition of field with given operator.
+func (f FieldRef) cond(op interface{}, err error) Cond {
+	if f.err != nil {
+		return Cond{err: f.err}
+	}
+	if err != nil {
+		if len(f.name) > 0 {
+			err = fmt.Errorf("q: field %s: %v", f.name, err)
+		} else {
+			err = fmt.Errorf("q: %v", err)
+		}
+		return Cond{err: err}
+	}
+	if len(f.name) == 0 {
+		return Cond{cond: op, elem: true}
+	}
+	return Cond{cond: M{f.name: op}}
+}
+
+// Eq makes equality condition, nil value matches null values and missing fields.
+func (f FieldRef) Eq(value interface{}) Cond {
+	var v, err = checkValue(value, true)
+	return f.cond(v, err)
+}
+
+// Ne makes "not equal" condition.
+func (f FieldRef) Ne(value interface{}) Cond {
+	var v, err = checkValue(value, true)
+	return f.cond(NotEqual(v), err)
+}
+
+// Lt makes "<" condition.
+func (f FieldRef) Lt(value interface{}) Cond {
+	var v, err = checkOrdered(value)
+	return f.cond(LT(v), err)
+}
+
+// Lte makes "<=" condition.
+func (f FieldRef) Lte(value interface{}) Cond {
+	var v, err = checkOrdered(value)
+	return f.cond(LTE(v), err)
+}
+
+// Gt makes ">" condition.
+func (f FieldRef) Gt(value interface{}) Cond {
+	var v, err = checkOrdered(value)
+	return f.cond(GT(v), err)
+}
+
+// Gte makes ">=" condition.
+func (f FieldRef) Gte(value interface{}) Cond {
+	var v, err = checkOrdered(value)
+	return f.cond(GTE(v), err)
+}
+
+// Between makes condition matching values in closed range from min to max.
+func (f FieldRef) Between(min, max interface{}) Cond {
+	return f.Gte(min).And(f.Lte(max))
+}
+
+// In makes condition matching any of given values.
+func (f FieldRef) In(values ...interface{}) Cond {
+	var list, err = checkValues(values, true)
+	return f.cond(In(list), err)
+}
+
+// NotIn makes condition matching none of given values.
+func (f FieldRef) NotIn(values ...interface{}) Cond {
+	var list, err = checkValues(values, true)
+	return f.cond(NotIn(list), err)
+}
+
+// All makes condition matching arrays containing all given values.
+func (f FieldRef) All(values ...interface{}) Cond {
+	var list, err = checkValues(values, false)
+	if err == nil && len(list) == 0 {
+		err = errors.New("no values")
+	}
+	return f.cond(All(list), err)
+}
+
+// Size makes condition matching arrays with given number of elements.
+func (f FieldRef) Size(n int) Cond {
+	var err error
+	if n < 0 {
+		err = fmt.Errorf("negative size %d", n)
+	}
+	return f.cond(Size(n), err)
+}
+
+// ElemMatch makes condition matching arrays with element satisfying given condition,
+// use Elem to make conditions on element itself.
+func (f FieldRef) ElemMatch(c Cond) Cond {
+	var e, err = c.build()
+	if err != nil && f.err == nil {
+		return Cond{err: err}
+	}
+	return f.cond(ElemMatch{e}, nil)
+}
+
+// Exists makes condition matching documents having the field even if it is null.
+func (f FieldRef) Exists() Cond {
+	return f.cond(Exists(true), nil)
+}
+
+// NotExists makes condition matching documents without the field.
+func (f FieldRef) NotExists() Cond {
+	return f.cond(Exists(false), nil)
+}
+
+// IsNull makes condition matching null values and missing fields.
+func (f FieldRef) IsNull() Cond {
+	return f.cond(IsNull(), nil)
+}
+
+// Regex makes regular expression match condition, flags are combination of "i", "m" and "s".
+func (f FieldRef) Regex(pattern, flags string) Cond {
+	var err error
+	if strings.Trim(flags, "ims") != "" {
+		err = fmt.Errorf("invalid regular expression flags %q", flags)
+	} else if _, e := regexp.Compile(pattern); e != nil {
+		err = e
+	}
+	return f.cond(Regex(pattern, flags), err)
+}
+
+// Prefix makes "starts with" condition.
+func (f FieldRef) Prefix(s string) Cond {
+	return f.cond(Prefix(s), nil)
+}
+
+// IEqual makes case-insensitive "equal" condition.
+func (f FieldRef) IEqual(s string) Cond {
+	return f.cond(IEqual(s), nil)
+}
+
+// Near makes condition matching GeoJSON points within maxMeters of given location, zero means no limit.
+func (f FieldRef) Near(lng, lat, maxMeters float64) Cond {
+	var err = checkLocation(lng, lat)
+	if err == nil && maxMeters < 0 {
+		err = fmt.Errorf("negative distance %v", maxMeters)
+	}
+	return f.cond(Near(lng, lat, maxMeters), err)
+}
+
+// Within makes condition matching GeoJSON points inside of given shape (Box or Polygon).
+func (f FieldRef) Within(shape interface{}) Cond {
+	var err error
+	switch s := shape.(type) {
+	case Box:
+		err = checkLocation(s.MinLng, s.MinLat)
+		if err == nil {
+			err = checkLocation(s.MaxLng, s.MaxLat)
+		}
+		if err == nil && (s.MinLng > s.MaxLng || s.MinLat > s.MaxLat) {
+			err = errors.New("minimal corner of box is greater than maximal")
+		}
+	case Polygon:
+		if len(s) < 3 {
+			err = errors.New("polygon has less than 3 vertices")
+		}
+		for _, p := range s {
+			if err == nil {
+				err = checkLocation(p[0], p[1])
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported shape %T", shape)
+	}
+	return f.cond(Within(shape), err)
+}
+
+func checkLocation(lng, lat float64) error {
+	if lng < -180 || lng > 180 || lat < -90 || lat > 90 {
+		return fmt.Errorf("invalid location [%v, %v]", lng, lat)
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// checkValue validates operand of condition, pointers are dereferenced.
+// Operands are nil, booleans, numbers, strings, times and arrays of them.
+func checkValue(value interface{}, null bool) (interface{}, error) {
+	if value == nil {
+		if !null {
+			return nil, errors.New("nil value")
+		}
+		return nil, nil
+	}
+	var v = reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return checkValue(nil, null)
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), nil
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface(), nil
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if _, err := checkValue(v.Index(i).Interface(), true); err != nil {
+				return nil, err
+			}
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", value)
+}
+
+// checkOrdered validates operand of comparison, it should be number, string or time.
+func checkOrdered(value interface{}) (interface{}, error) {
+	var v, err = checkValue(value, false)
+	if err != nil {
+		return nil, err
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool, reflect.Slice, reflect.Array:
+		return nil, fmt.Errorf("value of type %T is not ordered", value)
+	}
+	return v, nil
+}
+
+func checkValues(values []interface{}, null bool) ([]interface{}, error) {
+	var list = make([]interface{}, len(values))
+	for i, value := range values {
+		var v, err = checkValue(value, null)
+		if err != nil {
+			return nil, err
+		}
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Slice, reflect.Array:
+			return nil, fmt.Errorf("unsupported value of type %T", value)
+		}
+		list[i] = v
+	}
+	return list, nil
+}
@@ -0,0 +1,188 @@
+package q
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ExampleOption changes how Example makes filter.
+type ExampleOption func(*example)
+
+// IncludeZero makes Example match zero values of given fields (dotted paths of nested fields
+// are allowed, names of nested structs include all their fields) or of all fields if none given.
+// Nil pointers match null values and missing fields, zero values given by pointers are always matched.
+func IncludeZero(fields ...string) ExampleOption {
+	return func(e *example) {
+		if len(fields) == 0 {
+			e.zero = true
+			return
+		}
+		for _, f := range fields {
+			e.zeroFields[f] = true
+		}
+	}
+}
+
+// SkipNested makes Example ignore fields of nested structs, by default they are matched by dotted paths.
+func SkipNested() ExampleOption {
+	return func(e *example) {
+		e.skipNested = true
+	}
+}
+
+type example struct {
+	zero       bool
+	zeroFields map[string]bool
+	skipNested bool
+	filter     M
+}
+
+// Example makes filter matching documents like given struct (or pointer to struct), e.g.
+//
+//	users.Find(q.Example(&User{Name: "bob", Age: 20}))
+//
+// Exported fields with non-zero values are matched by equality, field names are taken from
+// json tags (bson tags if there are no json tags) like documents are indexed by stores.
+// Fields of embedded structs are inlined unless their types are unexported.
+// Non-empty slices and arrays match arrays containing all their elements, maps and empty slices
+// are skipped, so is empty id. Example without matching fields makes empty M which is not valid filter.
+func Example(value interface{}, opts ...ExampleOption) M {
+	var e = &example{
+		zeroFields: make(map[string]bool),
+		filter:     M{},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	var v = reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type() == timeType {
+		panic(fmt.Sprintf("q: example of type %T is not struct", value))
+	}
+	e.fields(v, "")
+	return e.filter
+}
+
+func (e *example) fields(v reflect.Value, prefix string) {
+	var t = v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		var f = t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+
+		var name, tagged = exampleName(f)
+		if name == "-" {
+			continue
+		}
+		if len(prefix) == 0 && name == "_id" {
+			name = "id"
+		}
+
+		var fv = v.Field(i)
+		// fields of embedded structs are inlined like encoding/json does
+		if f.Anonymous && !tagged {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				e.fields(fv, prefix)
+			}
+			continue
+		}
+		e.field(fv, prefix+name)
+	}
+}
+
+// exampleName returns document field name of struct field and whether it is given by tag.
+func exampleName(f reflect.StructField) (string, bool) {
+	for _, key := range []string{"json", "bson"} {
+		var tag = f.Tag.Get(key)
+		if len(tag) == 0 {
+			continue
+		}
+		var name = strings.Split(tag, ",")[0]
+		if len(name) > 0 {
+			return name, true
+		}
+	}
+	return f.Name, false
+}
+
+func (e *example) field(v reflect.Value, path string) {
+	// zero values given by pointers are matched
+	var set bool
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			if e.isZeroAllowed(path) {
+				e.filter[path] = nil
+			}
+			return
+		}
+		set = true
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			e.value(v, path, set)
+			return
+		}
+		if !e.skipNested {
+			e.fields(v, path+".")
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return
+		}
+		var list = make(All, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			var item, err = checkValue(v.Index(i).Interface(), true)
+			if err != nil {
+				return
+			}
+			list = append(list, item)
+		}
+		e.filter[path] = list
+	case reflect.Map, reflect.Chan, reflect.Func:
+	default:
+		e.value(v, path, set)
+	}
+}
+
+func (e *example) value(v reflect.Value, path string, set bool) {
+	// documents always have non-empty id
+	if isZero(v) && (path == "id" || !(set || e.isZeroAllowed(path))) {
+		return
+	}
+	e.filter[path] = v.Interface()
+}
+
+// isZeroAllowed checks whether zero value of field or of its parent fields is matched.
+func (e *example) isZeroAllowed(path string) bool {
+	if e.zero {
+		return true
+	}
+	for {
+		if e.zeroFields[path] {
+			return true
+		}
+		var i = strings.LastIndex(path, ".")
+		if i < 0 {
+			return false
+		}
+		path = path[:i]
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
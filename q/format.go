@@ -0,0 +1,249 @@
+package q
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// nesting levels of conditions defining where parentheses are required
+const (
+	levelTop = iota
+	levelOr
+	levelAnd
+	levelNot
+)
+
+var identRe = regexp.MustCompile(`^[\pL_][\pL\pN_.]*$`)
+
+// Format formats filter as expression accepted by Parse, fields of M are joined with "and" in order of names.
+// Slices and maps are formatted as arrays and objects, values of other types not supported
+// by Parse (e.g. time) are formatted as strings,
+// invalid conditions are printed with %v, so Format never fails on filters being logged.
+func Format(filter interface{}) string {
+	var f = &formatter{}
+	f.condition(filter, levelTop)
+	return f.buf.String()
+}
+
+type formatter struct {
+	buf bytes.Buffer
+	// nesting level of elem conditions
+	elem int
+}
+
+func (f *formatter) write(a ...string) {
+	for _, s := range a {
+		f.buf.WriteString(s)
+	}
+}
+
+func (f *formatter) condition(c interface{}, level int) {
+	switch t := c.(type) {
+	case Or:
+		f.join(t, " or ", levelOr, level >= levelOr)
+	case And:
+		f.join(t, " and ", levelAnd, level >= levelAnd)
+	case Not:
+		f.write("not ")
+		f.condition(t.Condition, levelNot)
+	case M:
+		var names = make([]string, 0, len(t))
+		for k := range t {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		var paren = len(names) > 1 && level >= levelNot
+		if paren {
+			f.write("(")
+		}
+		for i, k := range names {
+			if i > 0 {
+				f.write(" and ")
+			}
+			f.write(formatField(k), " ")
+			f.operation(t[k])
+		}
+		if paren {
+			f.write(")")
+		}
+	case TextSearch:
+		var fn = "text"
+		if t.All {
+			fn = "textall"
+		}
+		f.write(fn, "(", strconv.Quote(t.Search), ")")
+	case VectorSearch:
+		var list = make([]string, len(t.Vector))
+		for i, v := range t.Vector {
+			list[i] = formatFloat(float64(v), 32)
+		}
+		f.write("nearest(", formatField(t.Field), ", [", strings.Join(list, ", "), "], ",
+			strconv.Itoa(t.K), ", ", string(t.Metric), ")")
+	default:
+		if f.elem == 0 {
+			// not a condition, printed as is for logs
+			f.write(fmt.Sprintf("%v", c))
+			return
+		}
+		// operation on array element itself
+		f.operation(c)
+	}
+}
+
+func (f *formatter) join(list []interface{}, sep string, level int, paren bool) {
+	if paren {
+		f.write("(")
+	}
+	for i, c := range list {
+		if i > 0 {
+			f.write(sep)
+		}
+		f.condition(c, level)
+	}
+	if paren {
+		f.write(")")
+	}
+}
+
+// operation formats operator and its operand.
+func (f *formatter) operation(v interface{}) {
+	switch t := v.(type) {
+	case Op:
+		switch t.Kind {
+		case OpRegex:
+			var re, _ = t.Value.(RegexValue)
+			f.write("~ /", strings.Replace(re.Pattern, "/", `\/`, -1), "/", re.Flags)
+		case OpPrefix:
+			f.write("prefix ", formatValue(t.Value))
+		case OpIEqual:
+			f.write("iequal ", formatValue(t.Value))
+		case OpExists:
+			if exists, _ := t.Value.(bool); exists {
+				f.write("exists")
+			} else {
+				f.write("not exists")
+			}
+		default:
+			f.write(formatOp[t.Kind], " ", formatValue(t.Value))
+		}
+	case In:
+		f.write("in ", formatList(t))
+	case NotIn:
+		f.write("not in ", formatList(t))
+	case All:
+		f.write("all ", formatList(t))
+	case Size:
+		f.write("size ", strconv.Itoa(int(t)))
+	case ElemMatch:
+		f.write("elem (")
+		f.elem++
+		f.condition(t.Condition, levelTop)
+		f.elem--
+		f.write(")")
+	case GeoNear:
+		f.write("near (", formatFloat(t.Lng, 64), ", ", formatFloat(t.Lat, 64))
+		if t.MaxDistance > 0 {
+			f.write(", ", formatFloat(t.MaxDistance, 64))
+		}
+		f.write(")")
+	case GeoWithin:
+		switch s := t.Shape.(type) {
+		case Box:
+			f.write("within box(", formatFloat(s.MinLng, 64), ", ", formatFloat(s.MinLat, 64), ", ",
+				formatFloat(s.MaxLng, 64), ", ", formatFloat(s.MaxLat, 64), ")")
+		case Polygon:
+			var points = make([]string, len(s))
+			for i, p := range s {
+				points[i] = "(" + formatFloat(p[0], 64) + ", " + formatFloat(p[1], 64) + ")"
+			}
+			f.write("within polygon(", strings.Join(points, ", "), ")")
+		default:
+			f.write("within ", fmt.Sprintf("%v", t.Shape))
+		}
+	default:
+		f.write("= ", formatValue(v))
+	}
+}
+
+var formatOp = map[OpKind]string{
+	OpLT:  "<",
+	OpLTE: "<=",
+	OpGT:  ">",
+	OpGTE: ">=",
+	OpNE:  "!=",
+}
+
+// formatField quotes field names which are not identifiers or clash with keywords.
+func formatField(name string) string {
+	switch strings.ToLower(name) {
+	case "and", "or", "not":
+		return "`" + name + "`"
+	}
+	if !identRe.MatchString(name) {
+		return "`" + name + "`"
+	}
+	return name
+}
+
+func formatList(list []interface{}) string {
+	var a = make([]string, len(list))
+	for i, v := range list {
+		a[i] = formatValue(v)
+	}
+	return "(" + strings.Join(a, ", ") + ")"
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	var rv = reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.String:
+		return strconv.Quote(rv.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32:
+		return formatFloat(rv.Float(), 32)
+	case reflect.Float64:
+		return formatFloat(rv.Float(), 64)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "null"
+		}
+		var a = make([]string, rv.Len())
+		for i := range a {
+			a[i] = formatValue(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(a, ", ") + "]"
+	case reflect.Map:
+		if rv.IsNil() {
+			return "null"
+		}
+		var a []string
+		for _, k := range rv.MapKeys() {
+			a = append(a, strconv.Quote(fmt.Sprint(k.Interface()))+": "+formatValue(rv.MapIndex(k).Interface()))
+		}
+		sort.Strings(a)
+		return "{" + strings.Join(a, ", ") + "}"
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// formatFloat formats float so that it is parsed as float again.
+func formatFloat(v float64, bits int) string {
+	var s = strconv.FormatFloat(v, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eEIN") {
+		s += ".0"
+	}
+	return s
+}
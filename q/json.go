@@ -0,0 +1,744 @@
+package q
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// JSONError is validation error of JSON filter.
+type JSONError struct {
+	// Path of invalid element, e.g. "$or[1].age.$gte".
+	Path string
+	Msg  string
+}
+
+func (e *JSONError) Error() string {
+	if len(e.Path) == 0 {
+		return "q: " + e.Msg
+	}
+	return fmt.Sprintf("q: %s: %s", e.Path, e.Msg)
+}
+
+// ToJSON converts filter to MongoDB style JSON document made by ToDocument.
+func ToJSON(filter interface{}) ([]byte, error) {
+	doc, err := ToDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// ToDocument converts filter to MongoDB style document, mongo store queries by this document.
+// Box of GeoWithin is GeoJSON polygon, text search of all terms quotes each term,
+// vector search is {"$vectorSearch": {"path", "queryVector", "limit", "metric"}}.
+// Values of filter are kept as is.
+func ToDocument(filter interface{}) (result map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*JSONError)
+			if !ok {
+				panic(r)
+			}
+			result, err = nil, e
+		}
+	}()
+	return jsonCondition(filter, ""), nil
+}
+
+// FromJSON converts MongoDB style JSON document to filter validating its operators and values.
+// Integer numbers are converted to int64, other numbers to float64.
+// Regular expressions made by ToJSON for Prefix and IEqual and polygons made for Box
+// are converted back to these conditions.
+func FromJSON(data []byte) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*JSONError)
+			if !ok {
+				panic(r)
+			}
+			result, err = nil, e
+		}
+	}()
+	var d = json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var doc interface{}
+	if err = d.Decode(&doc); err != nil {
+		return nil, &JSONError{Msg: err.Error()}
+	}
+	if d.More() {
+		return nil, &JSONError{Msg: "unexpected data after filter"}
+	}
+	return fromDocument(jsonObject(doc, ""), ""), nil
+}
+
+func jsonFail(path, format string, args ...interface{}) {
+	panic(&JSONError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func jsonPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func jsonIndex(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+// conversion to JSON
+
+type jsonDoc = map[string]interface{}
+
+func jsonCondition(c interface{}, path string) jsonDoc {
+	switch t := c.(type) {
+	case Not:
+		return jsonDoc{"$nor": []interface{}{jsonCondition(t.Condition, jsonPath(path, "$nor"))}}
+	case And:
+		return jsonDoc{"$and": jsonConditions(t, jsonPath(path, "$and"))}
+	case Or:
+		return jsonDoc{"$or": jsonConditions(t, jsonPath(path, "$or"))}
+	case TextSearch:
+		return jsonDoc{"$text": jsonDoc{"$search": textSearch(t)}}
+	case VectorSearch:
+		return jsonDoc{"$vectorSearch": jsonDoc{
+			"path":        t.Field,
+			"queryVector": t.Vector,
+			"limit":       t.K,
+			"metric":      t.Metric,
+		}}
+	case M:
+		var m = jsonDoc{}
+		for k, v := range t {
+			m[k] = jsonOp(v, jsonPath(path, k))
+		}
+		return m
+	}
+	jsonFail(path, "unsupported condition %T", c)
+	return nil
+}
+
+func jsonConditions(list []interface{}, path string) []interface{} {
+	var result = make([]interface{}, len(list))
+	for i, c := range list {
+		result[i] = jsonCondition(c, jsonIndex(path, i))
+	}
+	return result
+}
+
+// textSearch makes $search string of text search like mongo store.
+func textSearch(t TextSearch) string {
+	var terms = strings.FieldsFunc(t.Search, func(r rune) bool {
+		return r == ' ' || r == '"' || r == '-'
+	})
+	if t.All {
+		for i, s := range terms {
+			terms[i] = `"` + s + `"`
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+func jsonOp(v interface{}, path string) interface{} {
+	switch t := v.(type) {
+	case Op:
+		switch t.Kind {
+		case OpRegex:
+			var re, _ = t.Value.(RegexValue)
+			var m = jsonDoc{"$regex": re.Pattern}
+			if len(re.Flags) > 0 {
+				m["$options"] = re.Flags
+			}
+			return m
+		case OpPrefix:
+			return jsonDoc{"$regex": "^" + regexp.QuoteMeta(fmt.Sprint(t.Value))}
+		case OpIEqual:
+			return jsonDoc{"$regex": "^" + regexp.QuoteMeta(fmt.Sprint(t.Value)) + "$", "$options": "i"}
+		}
+		return jsonDoc{"$" + string(t.Kind): t.Value}
+	case In:
+		return jsonDoc{"$in": []interface{}(t)}
+	case NotIn:
+		return jsonDoc{"$nin": []interface{}(t)}
+	case All:
+		return jsonDoc{"$all": []interface{}(t)}
+	case Size:
+		return jsonDoc{"$size": int(t)}
+	case ElemMatch:
+		return jsonDoc{"$elemMatch": jsonElemMatch(t.Condition, jsonPath(path, "$elemMatch"))}
+	case GeoNear:
+		var near = jsonDoc{"$geometry": jsonDoc{"type": "Point", "coordinates": []float64{t.Lng, t.Lat}}}
+		if t.MaxDistance > 0 {
+			near["$maxDistance"] = t.MaxDistance
+		}
+		return jsonDoc{"$near": near}
+	case GeoWithin:
+		return jsonDoc{"$geoWithin": jsonDoc{"$geometry": jsonShape(t.Shape, path)}}
+	}
+	return v
+}
+
+// jsonShape converts shape of GeoWithin condition to GeoJSON polygon,
+// legacy $box does not match GeoJSON points.
+func jsonShape(shape interface{}, path string) jsonDoc {
+	var ring [][]float64
+	switch t := shape.(type) {
+	case Box:
+		ring = [][]float64{
+			{t.MinLng, t.MinLat},
+			{t.MaxLng, t.MinLat},
+			{t.MaxLng, t.MaxLat},
+			{t.MinLng, t.MaxLat},
+		}
+	case Polygon:
+		for _, p := range t {
+			ring = append(ring, []float64{p[0], p[1]})
+		}
+	default:
+		jsonFail(path, "unsupported shape %T", shape)
+	}
+	if len(ring) == 0 {
+		jsonFail(path, "empty polygon")
+	}
+	// GeoJSON rings are closed explicitly
+	var first, last = ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		ring = append(ring, first)
+	}
+	return jsonDoc{"type": "Polygon", "coordinates": [][][]float64{ring}}
+}
+
+// jsonElemMatch converts condition on array element like mongo store.
+func jsonElemMatch(c interface{}, path string) jsonDoc {
+	switch t := c.(type) {
+	case M:
+		return jsonCondition(t, path)
+	case And:
+		// operators and field conditions are merged into one document unless they clash
+		var m = jsonDoc{}
+		for i, v := range t {
+			for k, e := range jsonElemMatch(v, jsonIndex(path, i)) {
+				if _, ok := m[k]; ok {
+					var list = make([]interface{}, len(t))
+					for i, v := range t {
+						list[i] = jsonElemMatch(v, jsonIndex(path, i))
+					}
+					return jsonDoc{"$and": list}
+				}
+				m[k] = e
+			}
+		}
+		return m
+	case Or:
+		var list = make([]interface{}, len(t))
+		for i, v := range t {
+			list[i] = jsonElemMatch(v, jsonIndex(path, i))
+		}
+		return jsonDoc{"$or": list}
+	case Not:
+		return jsonDoc{"$not": jsonElemMatch(t.Condition, path)}
+	}
+	if m, ok := jsonOp(c, path).(jsonDoc); ok {
+		return m
+	}
+	return jsonDoc{"$eq": c}
+}
+
+// conversion from JSON
+
+func jsonObject(v interface{}, path string) jsonDoc {
+	m, ok := v.(jsonDoc)
+	if !ok {
+		jsonFail(path, "expected object")
+	}
+	return m
+}
+
+func jsonArray(v interface{}, path string) []interface{} {
+	a, ok := v.([]interface{})
+	if !ok {
+		jsonFail(path, "expected array")
+	}
+	return a
+}
+
+func jsonString(v interface{}, path string) string {
+	s, ok := v.(string)
+	if !ok {
+		jsonFail(path, "expected string")
+	}
+	return s
+}
+
+func jsonFloat(v interface{}, path string) float64 {
+	n, ok := v.(json.Number)
+	if !ok {
+		jsonFail(path, "expected number")
+	}
+	f, err := n.Float64()
+	if err != nil {
+		jsonFail(path, "invalid number %s", n)
+	}
+	return f
+}
+
+func jsonInt(v interface{}, path string) int64 {
+	n, ok := v.(json.Number)
+	if !ok {
+		jsonFail(path, "expected integer")
+	}
+	i, err := n.Int64()
+	if err != nil || i < 0 {
+		jsonFail(path, "expected non-negative integer")
+	}
+	return i
+}
+
+// jsonValue converts numbers of JSON value, objects are allowed only as literal values.
+func jsonValue(v interface{}, path string) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, err := t.Float64()
+		if err != nil {
+			jsonFail(path, "invalid number %s", t)
+		}
+		return f
+	case []interface{}:
+		var a = make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = jsonValue(e, jsonIndex(path, i))
+		}
+		return a
+	case jsonDoc:
+		var m = make(map[string]interface{}, len(t))
+		for k, e := range t {
+			if strings.HasPrefix(k, "$") {
+				jsonFail(jsonPath(path, k), "operator in value")
+			}
+			m[k] = jsonValue(e, jsonPath(path, k))
+		}
+		return m
+	}
+	return v
+}
+
+// jsonScalar converts operand of comparison operators.
+func jsonScalar(v interface{}, path string) interface{} {
+	switch v.(type) {
+	case []interface{}, jsonDoc:
+		jsonFail(path, "expected scalar value")
+	}
+	return jsonValue(v, path)
+}
+
+func jsonValues(v interface{}, path string) []interface{} {
+	var a = jsonArray(v, path)
+	var list = make([]interface{}, len(a))
+	for i, e := range a {
+		list[i] = jsonScalar(e, jsonIndex(path, i))
+	}
+	return list
+}
+
+func sortedKeys(m jsonDoc) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isOperators determines whether all keys of object are operators.
+func isOperators(m jsonDoc, path string) bool {
+	var ops = 0
+	for k := range m {
+		if strings.HasPrefix(k, "$") {
+			ops++
+		}
+	}
+	if ops > 0 && ops < len(m) {
+		jsonFail(path, "operators mixed with fields")
+	}
+	return ops > 0
+}
+
+// join makes filter of field conditions in one M and other conditions.
+func join(fields M, conds []interface{}) interface{} {
+	if len(fields) > 0 {
+		conds = append([]interface{}{fields}, conds...)
+	}
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return And(conds)
+}
+
+func fromDocument(doc jsonDoc, path string) interface{} {
+	if len(doc) == 0 {
+		jsonFail(path, "empty condition")
+	}
+	var fields = M{}
+	var conds []interface{}
+	for _, k := range sortedKeys(doc) {
+		var v, p = doc[k], jsonPath(path, k)
+		switch k {
+		case "$and":
+			conds = append(conds, And(fromDocuments(v, p)))
+		case "$or":
+			conds = append(conds, Or(fromDocuments(v, p)))
+		case "$nor":
+			var list = fromDocuments(v, p)
+			if len(list) == 1 {
+				conds = append(conds, Not{list[0]})
+			} else {
+				conds = append(conds, Not{Or(list)})
+			}
+		case "$text":
+			conds = append(conds, fromText(v, p))
+		case "$vectorSearch":
+			conds = append(conds, fromVector(v, p))
+		default:
+			if strings.HasPrefix(k, "$") {
+				jsonFail(p, "unknown operator")
+			}
+			conds = merge(fields, conds, fromField(k, v, p))
+		}
+	}
+	return join(fields, conds)
+}
+
+func fromDocuments(v interface{}, path string) []interface{} {
+	var a = jsonArray(v, path)
+	if len(a) == 0 {
+		jsonFail(path, "expected non-empty array")
+	}
+	var list = make([]interface{}, len(a))
+	for i, e := range a {
+		var p = jsonIndex(path, i)
+		list[i] = fromDocument(jsonObject(e, p), p)
+	}
+	return list
+}
+
+// merge adds conditions on one field to M unless the field has condition there already.
+func merge(fields M, conds []interface{}, list []interface{}) []interface{} {
+	for _, c := range list {
+		if m, ok := c.(M); ok && len(m) == 1 {
+			for k, v := range m {
+				if _, has := fields[k]; !has {
+					fields[k] = v
+					c = nil
+				}
+			}
+		}
+		if c != nil {
+			conds = append(conds, c)
+		}
+	}
+	return conds
+}
+
+// fromField converts value of field to list of conditions.
+func fromField(name string, v interface{}, path string) []interface{} {
+	m, ok := v.(jsonDoc)
+	if !ok || !isOperators(m, path) {
+		return []interface{}{M{name: jsonValue(v, path)}}
+	}
+	var list []interface{}
+	for _, c := range fromOps(m, path) {
+		list = append(list, M{name: c})
+	}
+	if n, ok := m["$not"]; ok {
+		var p = jsonPath(path, "$not")
+		var ops = jsonObject(n, p)
+		if !isOperators(ops, p) {
+			jsonFail(p, "expected operators")
+		}
+		var negated = fromField(name, ops, p)
+		if len(negated) == 1 {
+			list = append(list, Not{negated[0]})
+		} else {
+			list = append(list, Not{And(negated)})
+		}
+	}
+	return list
+}
+
+// fromOps converts operators of field or array element to list of conditions.
+func fromOps(m jsonDoc, path string) []interface{} {
+	var list []interface{}
+	if _, ok := m["$options"]; ok {
+		if _, ok := m["$regex"]; !ok {
+			jsonFail(jsonPath(path, "$options"), "$options without $regex")
+		}
+	}
+	for _, k := range sortedKeys(m) {
+		var v, p = m[k], jsonPath(path, k)
+		switch k {
+		case "$eq":
+			list = append(list, jsonScalar(v, p))
+		case "$lt", "$lte", "$gt", "$gte", "$ne":
+			list = append(list, Op{OpKind(k[1:]), jsonScalar(v, p)})
+		case "$exists":
+			b, ok := v.(bool)
+			if !ok {
+				jsonFail(p, "expected boolean")
+			}
+			list = append(list, Exists(b))
+		case "$in":
+			list = append(list, In(jsonValues(v, p)))
+		case "$nin":
+			list = append(list, NotIn(jsonValues(v, p)))
+		case "$all":
+			list = append(list, All(jsonValues(v, p)))
+		case "$size":
+			list = append(list, Size(jsonInt(v, p)))
+		case "$regex":
+			var flags string
+			if o, ok := m["$options"]; ok {
+				flags = jsonString(o, jsonPath(path, "$options"))
+				if strings.Trim(flags, "ims") != "" {
+					jsonFail(jsonPath(path, "$options"), "invalid regular expression options %q", flags)
+				}
+			}
+			var pattern = jsonString(v, p)
+			if _, err := regexp.Compile(pattern); err != nil {
+				jsonFail(p, "invalid regular expression: %v", err)
+			}
+			list = append(list, fromRegex(pattern, flags))
+		case "$options":
+			// handled with $regex
+		case "$elemMatch":
+			list = append(list, ElemMatch{fromElemMatch(jsonObject(v, p), p)})
+		case "$near":
+			list = append(list, fromNear(jsonObject(v, p), p))
+		case "$geoWithin":
+			list = append(list, GeoWithin{fromShape(jsonObject(v, p), p)})
+		case "$not":
+			// negation of field operators is made by fromField, array elements are negated by fromElemMatch
+		default:
+			jsonFail(p, "unknown operator")
+		}
+	}
+	return list
+}
+
+// fromRegex converts regular expression to Prefix or IEqual if it is made for them.
+func fromRegex(pattern, flags string) interface{} {
+	if s, ok := unquoteMeta(strings.TrimPrefix(pattern, "^")); ok && strings.HasPrefix(pattern, "^") && len(flags) == 0 {
+		return Prefix(s)
+	}
+	if strings.HasPrefix(pattern, "^") && strings.HasSuffix(pattern, "$") && flags == "i" {
+		if s, ok := unquoteMeta(pattern[1 : len(pattern)-1]); ok {
+			return IEqual(s)
+		}
+	}
+	return Regex(pattern, flags)
+}
+
+// unquoteMeta returns literal text of pattern made by regexp.QuoteMeta.
+func unquoteMeta(pattern string) (string, bool) {
+	var buf []byte
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		buf = append(buf, pattern[i])
+	}
+	var s = string(buf)
+	return s, regexp.QuoteMeta(s) == pattern
+}
+
+func fromElemMatch(m jsonDoc, path string) interface{} {
+	if len(m) == 0 {
+		jsonFail(path, "empty condition")
+	}
+	var fields = M{}
+	var conds []interface{}
+	var ops = jsonDoc{}
+	for _, k := range sortedKeys(m) {
+		var v, p = m[k], jsonPath(path, k)
+		switch {
+		case k == "$and" || k == "$or":
+			var a = jsonArray(v, p)
+			if len(a) == 0 {
+				jsonFail(p, "expected non-empty array")
+			}
+			var list = make([]interface{}, len(a))
+			for i, e := range a {
+				var ep = jsonIndex(p, i)
+				list[i] = fromElemMatch(jsonObject(e, ep), ep)
+			}
+			if k == "$and" {
+				conds = append(conds, And(list))
+			} else {
+				conds = append(conds, Or(list))
+			}
+		case k == "$not":
+			conds = append(conds, Not{fromElemMatch(jsonObject(v, p), p)})
+		case strings.HasPrefix(k, "$"):
+			ops[k] = v
+		default:
+			conds = merge(fields, conds, fromField(k, v, p))
+		}
+	}
+	if len(ops) > 0 {
+		conds = append(fromOps(ops, path), conds...)
+	}
+	return join(fields, conds)
+}
+
+func fromText(v interface{}, path string) interface{} {
+	var m = jsonObject(v, path)
+	for k, e := range m {
+		switch k {
+		case "$search", "$language":
+			jsonString(e, jsonPath(path, k))
+		default:
+			jsonFail(jsonPath(path, k), "unknown text search option")
+		}
+	}
+	s, ok := m["$search"]
+	if !ok {
+		jsonFail(path, "$search is required")
+	}
+	var terms = strings.Fields(s.(string))
+	var all = len(terms) > 0
+	for i, t := range terms {
+		if len(t) < 2 || !strings.HasPrefix(t, `"`) || !strings.HasSuffix(t, `"`) {
+			all = false
+		}
+		terms[i] = strings.Trim(t, `"`)
+	}
+	return TextSearch{Search: strings.Join(terms, " "), All: all}
+}
+
+func fromVector(v interface{}, path string) interface{} {
+	var m = jsonObject(v, path)
+	var vs VectorSearch
+	for k, e := range m {
+		var p = jsonPath(path, k)
+		switch k {
+		case "path":
+			vs.Field = jsonString(e, p)
+		case "queryVector":
+			for i, x := range jsonArray(e, p) {
+				vs.Vector = append(vs.Vector, float32(jsonFloat(x, jsonIndex(p, i))))
+			}
+		case "limit":
+			vs.K = int(jsonInt(e, p))
+		case "metric":
+			vs.Metric = VectorMetric(jsonString(e, p))
+			switch vs.Metric {
+			case Cosine, Euclidean, DotProduct:
+			default:
+				jsonFail(p, "unknown metric %q", vs.Metric)
+			}
+		default:
+			jsonFail(p, "unknown vector search option")
+		}
+	}
+	if len(vs.Field) == 0 || len(vs.Vector) == 0 || len(vs.Metric) == 0 {
+		jsonFail(path, "path, queryVector and metric are required")
+	}
+	return vs
+}
+
+func fromNear(m jsonDoc, path string) interface{} {
+	var near GeoNear
+	for k, e := range m {
+		var p = jsonPath(path, k)
+		switch k {
+		case "$geometry":
+			var lng, lat = fromPoint(e, p)
+			near.Lng, near.Lat = lng, lat
+		case "$maxDistance":
+			near.MaxDistance = jsonFloat(e, p)
+			if near.MaxDistance < 0 {
+				jsonFail(p, "negative distance")
+			}
+		default:
+			jsonFail(p, "unknown operator")
+		}
+	}
+	if _, ok := m["$geometry"]; !ok {
+		jsonFail(path, "$geometry is required")
+	}
+	return near
+}
+
+func fromPoint(v interface{}, path string) (float64, float64) {
+	var m = jsonObject(v, path)
+	if m["type"] != "Point" || len(m) != 2 {
+		jsonFail(path, "expected GeoJSON point")
+	}
+	var p = jsonPath(path, "coordinates")
+	var c = jsonArray(m["coordinates"], p)
+	if len(c) != 2 {
+		jsonFail(p, "expected [lng, lat]")
+	}
+	return jsonFloat(c[0], jsonIndex(p, 0)), jsonFloat(c[1], jsonIndex(p, 1))
+}
+
+func fromShape(m jsonDoc, path string) interface{} {
+	if len(m) != 1 {
+		jsonFail(path, "expected $box or $geometry")
+	}
+	if v, ok := m["$box"]; ok {
+		var p = jsonPath(path, "$box")
+		var corners = fromCoords(v, p)
+		if len(corners) != 2 {
+			jsonFail(p, "expected [[minLng, minLat], [maxLng, maxLat]]")
+		}
+		return Box{MinLng: corners[0][0], MinLat: corners[0][1], MaxLng: corners[1][0], MaxLat: corners[1][1]}
+	}
+	v, ok := m["$geometry"]
+	if !ok {
+		jsonFail(path, "expected $box or $geometry")
+	}
+	var p = jsonPath(path, "$geometry")
+	var g = jsonObject(v, p)
+	if g["type"] != "Polygon" || len(g) != 2 {
+		jsonFail(p, "expected GeoJSON polygon")
+	}
+	var rings = jsonArray(g["coordinates"], jsonPath(p, "coordinates"))
+	if len(rings) != 1 {
+		jsonFail(jsonPath(p, "coordinates"), "polygons with holes are not supported")
+	}
+	var ring = fromCoords(rings[0], jsonIndex(jsonPath(p, "coordinates"), 0))
+	// ring of Polygon is closed implicitly
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		jsonFail(p, "expected at least 3 vertices")
+	}
+	// polygon made by ToDocument for Box
+	if len(ring) == 4 && ring[0][1] == ring[1][1] && ring[1][0] == ring[2][0] &&
+		ring[2][1] == ring[3][1] && ring[3][0] == ring[0][0] {
+		return Box{MinLng: ring[0][0], MinLat: ring[0][1], MaxLng: ring[2][0], MaxLat: ring[2][1]}
+	}
+	return Polygon(ring)
+}
+
+func fromCoords(v interface{}, path string) [][2]float64 {
+	var a = jsonArray(v, path)
+	var list = make([][2]float64, len(a))
+	for i, e := range a {
+		var p = jsonIndex(path, i)
+		var c = jsonArray(e, p)
+		if len(c) != 2 {
+			jsonFail(p, "expected [lng, lat]")
+		}
+		list[i] = [2]float64{jsonFloat(c[0], jsonIndex(p, 0)), jsonFloat(c[1], jsonIndex(p, 1))}
+	}
+	return list
+}
@@ -1,3 +1,14 @@
+// Package q defines query model shared by all data stores.
+//
+// Missing fields and null values follow MongoDB semantics in every store:
+//
+//   - Exists(true) matches documents having the field even if it is null,
+//     Exists(false) matches documents without the field.
+//   - IsNull() (the same as equality with nil) matches null values and missing fields.
+//   - Equality, In and comparison operators never match null values or missing fields
+//     unless nil is given as operand.
+//   - NotEqual and NotIn match null values and missing fields, they are negations of
+//     equality and In, so does Not for any condition.
 package q
 
 // M is map of field conditions.
@@ -20,6 +31,76 @@ type In []interface{}
 // NotIn operator.
 type NotIn []interface{}
 
+// All operator matches arrays containing all given values.
+type All []interface{}
+
+// Size operator matches arrays with given number of elements.
+type Size int
+
+// ElemMatch operator matches arrays with at least one element satisfying the condition.
+// Condition is a query over fields of element (e.g. M) or an operator applied to element itself (e.g. GT(1)).
+// And, Or and Not combine both kinds of conditions.
+type ElemMatch struct {
+	Condition interface{}
+}
+
+// TextSearch is full-text search condition over text fields of collection.
+// Terms of search string are stemmed and stop words are ignored.
+// Documents are ordered by relevance unless results are sorted explicitly.
+type TextSearch struct {
+	Search string
+	// All requires documents to contain all terms instead of any of them.
+	All bool
+}
+
+// GeoNear operator matches GeoJSON points within MaxDistance meters of given location,
+// zero MaxDistance means no limit. Documents are ordered by distance unless results are sorted explicitly.
+type GeoNear struct {
+	Lng         float64
+	Lat         float64
+	MaxDistance float64
+}
+
+// GeoWithin operator matches GeoJSON points inside of given shape (Box or Polygon).
+type GeoWithin struct {
+	Shape interface{}
+}
+
+// Box is rectangle with edges along meridians and parallels.
+type Box struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// Polygon is ring of [lng, lat] vertices, the ring is closed implicitly.
+// Edges are straight lines in lng/lat plane, mongo uses geodesic edges.
+type Polygon [][2]float64
+
+// VectorMetric defines distance of vectors.
+type VectorMetric string
+
+const (
+	// Cosine distance is 1 - cosine similarity of vectors.
+	Cosine VectorMetric = "cosine"
+	// Euclidean distance.
+	Euclidean VectorMetric = "euclidean"
+	// DotProduct distance is negative inner product of vectors.
+	DotProduct VectorMetric = "dot"
+)
+
+// VectorSearch is top-level condition matching K documents with vector field
+// nearest to given vector, zero K means all documents with vector field.
+// Other conditions of query filter documents before search.
+// Documents are ordered by distance unless results are sorted explicitly.
+type VectorSearch struct {
+	Field  string
+	Vector []float32
+	K      int
+	Metric VectorMetric
+}
+
 // Op condition.
 type Op struct {
 	Kind  OpKind
@@ -40,8 +121,23 @@ const (
 	OpGTE OpKind = "gte"
 	// OpNE defines "not equal" operator.
 	OpNE OpKind = "ne"
+	// OpRegex defines regular expression match operator, value is RegexValue.
+	OpRegex OpKind = "regex"
+	// OpPrefix defines "starts with" operator.
+	OpPrefix OpKind = "prefix"
+	// OpIEqual defines case-insensitive "equal" operator.
+	OpIEqual OpKind = "iequal"
+	// OpExists defines field existence operator, value is bool.
+	OpExists OpKind = "exists"
 )
 
+// RegexValue is value of regular expression condition.
+type RegexValue struct {
+	Pattern string
+	// Flags are combination of "i" (case-insensitive), "m" (multi-line) and "s" (dot matches new line).
+	Flags string
+}
+
 // LT makes "<" condition.
 func LT(value interface{}) interface{} {
 	return Op{OpLT, value}
@@ -66,3 +162,53 @@ func GTE(value interface{}) interface{} {
 func NotEqual(value interface{}) interface{} {
 	return Op{OpNE, value}
 }
+
+// Regex makes regular expression match condition.
+func Regex(pattern, flags string) interface{} {
+	return Op{OpRegex, RegexValue{pattern, flags}}
+}
+
+// Prefix makes "starts with" condition.
+func Prefix(s string) interface{} {
+	return Op{OpPrefix, s}
+}
+
+// IEqual makes case-insensitive "equal" condition.
+func IEqual(s string) interface{} {
+	return Op{OpIEqual, s}
+}
+
+// Exists makes condition on existence of field.
+func Exists(exists bool) interface{} {
+	return Op{OpExists, exists}
+}
+
+// IsNull makes condition matching null values and missing fields.
+func IsNull() interface{} {
+	return nil
+}
+
+// Text makes full-text search condition matching documents with any of terms of given search string.
+func Text(search string) interface{} {
+	return TextSearch{Search: search}
+}
+
+// TextAll makes full-text search condition matching documents with all terms of given search string.
+func TextAll(search string) interface{} {
+	return TextSearch{Search: search, All: true}
+}
+
+// Near makes condition matching GeoJSON points within maxMeters of given location.
+func Near(lng, lat, maxMeters float64) interface{} {
+	return GeoNear{Lng: lng, Lat: lat, MaxDistance: maxMeters}
+}
+
+// Within makes condition matching GeoJSON points inside of given shape (Box or Polygon).
+func Within(shape interface{}) interface{} {
+	return GeoWithin{Shape: shape}
+}
+
+// NearestVector makes condition matching k documents with vector field nearest to given vector.
+func NearestVector(field string, vec []float32, k int, metric VectorMetric) interface{} {
+	return VectorSearch{Field: field, Vector: vec, K: k, Metric: metric}
+}
@@ -0,0 +1,646 @@
+package q
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Normalize rewrites filter to canonical form, so stores get the same tree for equivalent filters:
+//
+//   - nested And and Or are flattened, And and Or with one condition are replaced by it;
+//   - Not is pushed down to fields by De Morgan's laws, negations of equality, NotEqual, In,
+//     NotIn and Exists are replaced by their opposite operators;
+//   - field conditions of And are merged into one M ordered by field name, comparisons of
+//     the same direction on the same field are merged into the tightest bound, In with one value
+//     becomes equality, conditions implied by equality are dropped;
+//   - contradictions (e.g. a = 1 and a <> 1, a = 1 and field a is missing) are detected.
+//
+// Fields may be arrays matched by any element, so distinct equalities or disjoint ranges
+// on the same field are kept as separate conditions.
+//
+// Comparisons are never negated (NOT a > 5 matches missing fields unlike a <= 5) and
+// conditions of ElemMatch are kept as is. Empty M matches all documents. Normalize returns
+// false if filter matches no documents and nil filter if it matches all documents.
+func Normalize(filter interface{}) (interface{}, bool) {
+	switch t := normalize(filter, false).(type) {
+	case truth:
+		return nil, bool(t)
+	default:
+		return t, true
+	}
+}
+
+// truth is condition matching all (true) or no (false) documents
+type truth bool
+
+// normalize returns canonical form of condition or of its negation.
+func normalize(c interface{}, neg bool) interface{} {
+	switch t := c.(type) {
+	case truth:
+		return truth(bool(t) != neg)
+	case Not:
+		return normalize(t.Condition, !neg)
+	case And:
+		if neg {
+			return normalizeOr(t, true)
+		}
+		return normalizeAnd(t, false)
+	case Or:
+		if neg {
+			return normalizeAnd(t, true)
+		}
+		return normalizeOr(t, false)
+	case M:
+		if len(t) == 0 {
+			// no field conditions, matches all documents
+			return truth(!neg)
+		}
+		if !neg {
+			return normalizeAnd([]interface{}{t}, false)
+		}
+		if len(t) == 1 {
+			return negateField(t)
+		}
+		var list = make([]interface{}, 0, len(t))
+		for _, name := range sortedFields(t) {
+			list = append(list, M{name: t[name]})
+		}
+		return normalizeOr(list, true)
+	}
+	if neg {
+		return Not{c}
+	}
+	return c
+}
+
+func normalizeAnd(list []interface{}, neg bool) interface{} {
+	var fields = make(map[string][]interface{})
+	var rest And
+	var add func(c interface{}) bool
+	add = func(c interface{}) bool {
+		switch t := c.(type) {
+		case truth:
+			return bool(t)
+		case And:
+			for _, e := range t {
+				if !add(e) {
+					return false
+				}
+			}
+		case M:
+			for _, name := range sortedFields(t) {
+				fields[name] = append(fields[name], t[name])
+			}
+		default:
+			rest = append(rest, c)
+		}
+		return true
+	}
+	for _, c := range list {
+		// field conditions are merged below
+		if m, ok := c.(M); ok && !neg {
+			add(m)
+			continue
+		}
+		if !add(normalize(c, neg)) {
+			return truth(false)
+		}
+	}
+
+	var result And
+	var main = M{}
+	var extra And
+	for _, name := range sortedFields(fields) {
+		var ops, ok = mergeField(fields[name])
+		if !ok {
+			return truth(false)
+		}
+		if len(ops) == 0 {
+			continue
+		}
+		main[name] = ops[0]
+		for _, op := range ops[1:] {
+			extra = append(extra, M{name: op})
+		}
+	}
+	if len(main) > 0 {
+		result = append(result, main)
+	}
+	result = append(result, extra...)
+	result = append(result, rest...)
+
+	switch len(result) {
+	case 0:
+		return truth(true)
+	case 1:
+		return result[0]
+	}
+	return result
+}
+
+func normalizeOr(list []interface{}, neg bool) interface{} {
+	var result Or
+	var add func(c interface{}) bool
+	add = func(c interface{}) bool {
+		switch t := c.(type) {
+		case truth:
+			return !bool(t)
+		case Or:
+			for _, e := range t {
+				if !add(e) {
+					return false
+				}
+			}
+		default:
+			result = append(result, c)
+		}
+		return true
+	}
+	for _, c := range list {
+		if !add(normalize(c, neg)) {
+			return truth(true)
+		}
+	}
+
+	switch len(result) {
+	case 0:
+		return truth(false)
+	case 1:
+		return result[0]
+	}
+	return result
+}
+
+// negateField returns negation of condition on one field.
+func negateField(m M) interface{} {
+	for name, v := range m {
+		switch t := v.(type) {
+		case In:
+			return normalize(M{name: NotIn(t)}, false)
+		case NotIn:
+			return normalize(M{name: In(t)}, false)
+		case Op:
+			switch t.Kind {
+			case OpNE:
+				if isScalar(t.Value) {
+					return normalize(M{name: t.Value}, false)
+				}
+			case OpExists:
+				if exists, ok := t.Value.(bool); ok {
+					return M{name: Exists(!exists)}
+				}
+			}
+		default:
+			if isScalar(v) {
+				return M{name: NotEqual(v)}
+			}
+		}
+	}
+	return Not{m}
+}
+
+// bound of range condition
+type bound struct {
+	value     interface{}
+	inclusive bool
+}
+
+// satisfies checks whether value is within bound, ok is false if value is not comparable with bound.
+func (b bound) satisfies(v interface{}, lower bool) (bool, bool) {
+	if v == nil {
+		// comparisons never match null values
+		return false, true
+	}
+	var c, ok = compareValues(v, b.value)
+	if !ok {
+		return false, false
+	}
+	if lower {
+		c = -c
+	}
+	return c < 0 || (c == 0 && b.inclusive), true
+}
+
+// tighter checks whether bound is tighter than given one, ok is false if bounds are not comparable.
+func (b bound) tighter(o bound, lower bool) (bool, bool) {
+	var c, ok = compareValues(b.value, o.value)
+	if !ok {
+		return false, false
+	}
+	if lower {
+		c = -c
+	}
+	return c < 0 || (c == 0 && !b.inclusive && o.inclusive), true
+}
+
+// fieldConds holds conditions on one field of And.
+type fieldConds struct {
+	eq     []interface{}
+	in     []In
+	ne     []interface{}
+	lower  []bound
+	upper  []bound
+	exists []bool
+	others []interface{}
+}
+
+func (f *fieldConds) addBound(list []bound, b bound, lower bool) []bound {
+	for i, o := range list {
+		if t, ok := b.tighter(o, lower); ok {
+			if t {
+				list[i] = b
+			}
+			return list
+		}
+	}
+	return append(list, b)
+}
+
+func (f *fieldConds) add(v interface{}) {
+	switch t := v.(type) {
+	case In:
+		f.in = append(f.in, t)
+	case NotIn:
+		f.ne = append(f.ne, t...)
+	case Op:
+		switch t.Kind {
+		case OpLT, OpLTE:
+			if isOrdered(t.Value) {
+				f.upper = f.addBound(f.upper, bound{t.Value, t.Kind == OpLTE}, false)
+				return
+			}
+		case OpGT, OpGTE:
+			if isOrdered(t.Value) {
+				f.lower = f.addBound(f.lower, bound{t.Value, t.Kind == OpGTE}, true)
+				return
+			}
+		case OpNE:
+			if isScalar(t.Value) {
+				f.ne = append(f.ne, t.Value)
+				return
+			}
+		case OpExists:
+			if exists, ok := t.Value.(bool); ok {
+				f.exists = append(f.exists, exists)
+				return
+			}
+		}
+		f.others = append(f.others, v)
+	default:
+		if isScalar(v) {
+			f.eq = append(f.eq, v)
+			return
+		}
+		f.others = append(f.others, v)
+	}
+}
+
+// mergeField merges conditions on one field of And, ok is false if they are contradictory.
+// Empty list is returned if conditions match all documents.
+// Field may be array, so distinct equalities or disjoint ranges are not contradictory
+// (array [1, 10] matches a = 1 and a = 10, a > 5 and a < 3), they are kept as separate conditions.
+func mergeField(conds []interface{}) ([]interface{}, bool) {
+	var f = &fieldConds{}
+	for _, c := range conds {
+		f.add(c)
+	}
+
+	// existence
+	var exists, notExists bool
+	for _, e := range f.exists {
+		exists = exists || e
+		notExists = notExists || !e
+	}
+	if exists && notExists {
+		return nil, false
+	}
+
+	// values excluded by negations (no array element equals them) or by missing field never match
+	var ne = dedupe(f.ne)
+	var excluded = func(v interface{}) bool {
+		if notExists && v != nil {
+			return true
+		}
+		for _, n := range ne {
+			if eq, known := equalValues(v, n); known && eq {
+				return true
+			}
+		}
+		return false
+	}
+
+	var eq = f.eq
+	var ins []In
+	for _, in := range f.in {
+		var list []interface{}
+		for _, v := range dedupe(in) {
+			if !excluded(v) {
+				list = append(list, v)
+			}
+		}
+		switch len(list) {
+		case 0:
+			return nil, false
+		case 1:
+			eq = append(eq, list[0])
+		default:
+			ins = append(ins, In(list))
+		}
+	}
+	eq = dedupe(eq)
+	for _, v := range eq {
+		if excluded(v) {
+			return nil, false
+		}
+	}
+
+	if notExists {
+		if len(f.lower) > 0 || len(f.upper) > 0 || hasNil(ne) {
+			return nil, false
+		}
+		// equalities and In are left with null value only, it is implied as so are negations of other values
+		return append([]interface{}{Exists(false)}, f.others...), true
+	}
+
+	var result []interface{}
+	for _, v := range eq {
+		result = append(result, v)
+	}
+	// In with any of equal values is implied
+	for _, in := range ins {
+		if list, known := intersect(eq, in); known && len(list) > 0 {
+			continue
+		}
+		result = append(result, in)
+	}
+	// bounds satisfied by equal value are implied
+	var implied = func(b bound, lower bool) bool {
+		for _, v := range eq {
+			if ok, known := b.satisfies(v, lower); known && ok {
+				return true
+			}
+		}
+		return false
+	}
+	var lower, upper []bound
+	for _, b := range f.lower {
+		if !implied(b, true) {
+			lower = append(lower, b)
+		}
+	}
+	for _, b := range f.upper {
+		if !implied(b, false) {
+			upper = append(upper, b)
+		}
+	}
+	f.lower, f.upper = lower, upper
+	result = f.appendBounds(result)
+	result = appendNe(result, ne)
+
+	// comparisons, values other than null and NotEqual(nil) imply existence of field
+	if exists && !impliesExists(eq, ins, len(f.lower)+len(f.upper) > 0, ne) {
+		result = append(result, Exists(true))
+	}
+	// empty result means that conditions match all documents
+	return append(result, f.others...), true
+}
+
+// impliesExists checks whether merged conditions on field imply its existence.
+func impliesExists(eq []interface{}, ins []In, bounds bool, ne []interface{}) bool {
+	if bounds || hasNil(ne) {
+		return true
+	}
+	for _, v := range eq {
+		if v != nil {
+			return true
+		}
+	}
+	for _, in := range ins {
+		if !hasNil(in) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNil(list []interface{}) bool {
+	for _, v := range list {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fieldConds) appendBounds(result []interface{}) []interface{} {
+	for _, b := range f.lower {
+		if b.inclusive {
+			result = append(result, GTE(b.value))
+		} else {
+			result = append(result, GT(b.value))
+		}
+	}
+	for _, b := range f.upper {
+		if b.inclusive {
+			result = append(result, LTE(b.value))
+		} else {
+			result = append(result, LT(b.value))
+		}
+	}
+	return result
+}
+
+func appendNe(result []interface{}, ne []interface{}) []interface{} {
+	ne = dedupe(ne)
+	switch len(ne) {
+	case 0:
+		return result
+	case 1:
+		return append(result, NotEqual(ne[0]))
+	}
+	return append(result, NotIn(ne))
+}
+
+// dedupe removes values known to be equal to previous ones.
+func dedupe(values []interface{}) []interface{} {
+	var list []interface{}
+	for _, v := range values {
+		var dup bool
+		for _, e := range list {
+			if eq, known := equalValues(v, e); known && eq {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// intersect returns values of list equal to any of given ones, known is false if some values are not comparable.
+func intersect(list []interface{}, values []interface{}) ([]interface{}, bool) {
+	var result []interface{}
+	for _, v := range list {
+		var found bool
+		for _, e := range values {
+			var eq, known = equalValues(v, e)
+			if !known {
+				return nil, false
+			}
+			found = found || eq
+		}
+		if found {
+			result = append(result, v)
+		}
+	}
+	return result, true
+}
+
+// value classes of ordering
+const (
+	classNone = iota
+	classNumber
+	classString
+	classTime
+	classBool
+)
+
+func valueClass(v interface{}) int {
+	switch v.(type) {
+	case time.Time:
+		return classTime
+	case Size:
+		return classNone
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return classNumber
+	case reflect.String:
+		return classString
+	case reflect.Bool:
+		return classBool
+	}
+	return classNone
+}
+
+func isOrdered(v interface{}) bool {
+	switch valueClass(v) {
+	case classNumber, classString, classTime:
+		return true
+	}
+	return false
+}
+
+// isScalar checks whether value is operand of equality, i.e. nil or value of ordering class.
+func isScalar(v interface{}) bool {
+	return v == nil || valueClass(v) != classNone
+}
+
+// compareValues compares numbers, strings and times, ok is false if values are not comparable.
+func compareValues(a, b interface{}) (int, bool) {
+	var ca, cb = valueClass(a), valueClass(b)
+	if ca != cb {
+		return 0, false
+	}
+	switch ca {
+	case classNumber:
+		var x, y = reflect.ValueOf(a), reflect.ValueOf(b)
+		if isInt(x) && isInt(y) {
+			return compareInts(x.Int(), y.Int()), true
+		}
+		return compareFloats(toFloat(x), toFloat(y)), true
+	case classString:
+		var x, y = reflect.ValueOf(a).String(), reflect.ValueOf(b).String()
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case classTime:
+		var x, y = a.(time.Time), b.(time.Time)
+		switch {
+		case x.Before(y):
+			return -1, true
+		case x.After(y):
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// equalValues compares values, known is false if it is unknown whether values are equal.
+func equalValues(a, b interface{}) (bool, bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, true
+	}
+	if c, ok := compareValues(a, b); ok {
+		return c == 0, true
+	}
+	var ca, cb = valueClass(a), valueClass(b)
+	if ca == classBool && cb == classBool {
+		return reflect.ValueOf(a).Bool() == reflect.ValueOf(b).Bool(), true
+	}
+	if ca != classNone && cb != classNone {
+		return false, true
+	}
+	if reflect.DeepEqual(a, b) {
+		return true, true
+	}
+	return false, false
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func compareInts(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func sortedFields(m interface{}) []string {
+	var v = reflect.ValueOf(m)
+	var names = make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	return names
+}
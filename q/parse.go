@@ -0,0 +1,618 @@
+package q
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseError is syntax error of filter expression.
+type ParseError struct {
+	// Offset is byte offset of error in expression.
+	Offset int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("q: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Parse parses filter expression to query model, e.g.
+//
+//	age >= 20 and name in ("bob", "rob") and not email = "x"
+//
+// Field conditions are joined with "and", "or", "not" and parentheses.
+// Field names are identifiers with dots of nested paths or any text in backquotes.
+// Values are double or single quoted strings, numbers, true, false, null,
+// arrays [v, ...] and objects {"key": v, ...}.
+// Operators of field conditions are:
+//
+//	= != < <= > >=         comparison, "= null" matches null values and missing fields
+//	~ /pattern/flags       regular expression, flags are "i", "m" and "s"
+//	in (...), not in (...) In and NotIn
+//	all (...), size n      All and Size of arrays
+//	elem (condition)       ElemMatch, condition may omit field to match elements itself
+//	prefix "s"             starts with
+//	iequal "s"             case-insensitive equality
+//	exists, not exists     existence of field
+//	near (lng, lat, max)   GeoNear, max distance in meters is optional
+//	within box(minLng, minLat, maxLng, maxLat)
+//	within polygon((lng, lat), ...)
+//
+// Full-text and vector searches are written as text("..."), textall("...")
+// and nearest(field, [x, y, ...], k, cosine|euclidean|dot).
+// Integer numbers are parsed as int64, other numbers as float64.
+func Parse(s string) (result interface{}, err error) {
+	var p = &parser{src: s}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*ParseError)
+			if !ok {
+				panic(r)
+			}
+			result, err = nil, e
+		}
+	}()
+	p.next()
+	if p.tok.kind == tokEOF {
+		p.fail(p.tok.pos, "empty expression")
+	}
+	result = p.or()
+	if p.tok.kind != tokEOF {
+		p.unexpected("and, or or end of expression")
+	}
+	return result, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokRegex
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokLBrace
+	tokRBrace
+	tokColon
+	tokComma
+)
+
+var punctuation = map[byte]tokenKind{
+	'(': tokLParen,
+	')': tokRParen,
+	'[': tokLBracket,
+	']': tokRBracket,
+	'{': tokLBrace,
+	'}': tokRBrace,
+	':': tokColon,
+	',': tokComma,
+}
+
+type token struct {
+	kind tokenKind
+	// source text of token, unquoted value of strings and quoted identifiers
+	text string
+	pos  int
+	// whether identifier is quoted with backquotes, quoted identifiers are not keywords
+	quoted bool
+	// flags of regular expression
+	flags string
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokRegex:
+		return "regular expression"
+	}
+	return "'" + t.text + "'"
+}
+
+type parser struct {
+	src string
+	// offset of next token
+	pos int
+	// current token
+	tok token
+	// nesting level of elem conditions
+	elem int
+}
+
+func (p *parser) fail(pos int, format string, args ...interface{}) {
+	panic(&ParseError{Offset: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) unexpected(expected string) {
+	p.fail(p.tok.pos, "unexpected %s, expected %s", p.tok, expected)
+}
+
+// next reads next token.
+func (p *parser) next() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	var start = p.pos
+	p.tok = token{pos: start}
+	if p.pos >= len(p.src) {
+		p.tok.kind = tokEOF
+		return
+	}
+
+	var c = p.src[p.pos]
+	switch {
+	case punctuation[c] != 0:
+		p.pos++
+		p.tok.kind = punctuation[c]
+		p.tok.text = string(c)
+	case strings.IndexByte("=!<>~", c) >= 0:
+		for _, op := range []string{"==", "!=", "<>", "<=", ">=", "=", "<", ">", "~"} {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += len(op)
+				p.tok.kind = tokOp
+				p.tok.text = op
+				return
+			}
+		}
+		p.fail(start, "unexpected character %q", c)
+	case c == '"' || c == '\'':
+		p.tok.kind = tokString
+		p.tok.text = p.quoted(c)
+	case c == '`':
+		var end = strings.IndexByte(p.src[p.pos+1:], '`')
+		if end < 0 {
+			p.fail(start, "unterminated field name")
+		}
+		p.tok.kind = tokIdent
+		p.tok.text = p.src[p.pos+1 : p.pos+1+end]
+		p.tok.quoted = true
+		p.pos += end + 2
+	case c == '/':
+		p.tok.kind = tokRegex
+		p.regex()
+	case isDigit(c) || ((c == '-' || c == '.') && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1])):
+		p.pos++
+		for p.pos < len(p.src) {
+			var d = p.src[p.pos]
+			if isDigit(d) || d == '.' || d == 'e' || d == 'E' ||
+				((d == '+' || d == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+				p.pos++
+				continue
+			}
+			break
+		}
+		p.tok.kind = tokNumber
+		p.tok.text = p.src[start:p.pos]
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !isIdentRune(r, true) {
+			p.fail(start, "unexpected character %q", r)
+		}
+		for p.pos < len(p.src) {
+			r, n := utf8.DecodeRuneInString(p.src[p.pos:])
+			if !isIdentRune(r, false) {
+				break
+			}
+			p.pos += n
+		}
+		p.tok.kind = tokIdent
+		p.tok.text = p.src[start:p.pos]
+	}
+}
+
+// quoted reads string literal with Go escape sequences.
+func (p *parser) quoted(quote byte) string {
+	var start = p.pos
+	var s = p.src[p.pos+1:]
+	var buf []byte
+	for {
+		if len(s) == 0 {
+			p.fail(start, "unterminated string")
+		}
+		if s[0] == quote {
+			p.pos = len(p.src) - len(s) + 1
+			return string(buf)
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(s, quote)
+		if err != nil {
+			p.fail(len(p.src)-len(s), "invalid escape sequence in string")
+		}
+		if r < utf8.RuneSelf || !multibyte {
+			buf = append(buf, byte(r))
+		} else {
+			var b [utf8.UTFMax]byte
+			var n = utf8.EncodeRune(b[:], r)
+			buf = append(buf, b[:n]...)
+		}
+		s = tail
+	}
+}
+
+// regex reads regular expression literal, slash is escaped with backslash in pattern.
+func (p *parser) regex() {
+	var start = p.pos
+	var buf []byte
+	p.pos++
+	for {
+		if p.pos >= len(p.src) {
+			p.fail(start, "unterminated regular expression")
+		}
+		var c = p.src[p.pos]
+		if c == '/' {
+			p.pos++
+			break
+		}
+		if c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/' {
+			c = '/'
+			p.pos++
+		}
+		buf = append(buf, c)
+		p.pos++
+	}
+	var flags = p.pos
+	for p.pos < len(p.src) && strings.IndexByte("ims", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	if p.pos < len(p.src) && unicode.IsLetter(rune(p.src[p.pos])) {
+		p.fail(p.pos, "invalid regular expression flag %q", p.src[p.pos])
+	}
+	p.tok.text = string(buf)
+	p.tok.flags = p.src[flags:p.pos]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentRune(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && (r == '.' || unicode.IsDigit(r))
+}
+
+// keyword determines whether current token is given keyword, keywords are case-insensitive.
+func (p *parser) keyword(k string) bool {
+	return p.tok.kind == tokIdent && !p.tok.quoted && strings.EqualFold(p.tok.text, k)
+}
+
+func (p *parser) expect(kind tokenKind, expected string) token {
+	var t = p.tok
+	if t.kind != kind {
+		p.unexpected(expected)
+	}
+	p.next()
+	return t
+}
+
+func (p *parser) or() interface{} {
+	var list = Or{p.and()}
+	for p.keyword("or") {
+		p.next()
+		list = append(list, p.and())
+	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return list
+}
+
+func (p *parser) and() interface{} {
+	var list = And{p.unary()}
+	for p.keyword("and") {
+		p.next()
+		list = append(list, p.unary())
+	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return list
+}
+
+func (p *parser) unary() interface{} {
+	if p.elem > 0 {
+		if c, ok := p.elemOperation(); ok {
+			return c
+		}
+	}
+	if p.keyword("not") {
+		p.next()
+		return Not{p.unary()}
+	}
+	return p.primary()
+}
+
+// elemOperation tries to parse operation applied to array element itself.
+func (p *parser) elemOperation() (result interface{}, ok bool) {
+	var pos, tok = p.pos, p.tok
+	defer func() {
+		if r := recover(); r != nil {
+			if _, e := r.(*ParseError); !e {
+				panic(r)
+			}
+			ok = false
+		}
+		if !ok {
+			p.pos, p.tok = pos, tok
+		}
+	}()
+	result = p.operation()
+	var end = p.tok.kind == tokEOF || p.tok.kind == tokRParen || p.keyword("and") || p.keyword("or")
+	return result, end
+}
+
+func (p *parser) primary() interface{} {
+	if p.tok.kind == tokLParen {
+		p.next()
+		var c = p.or()
+		p.expect(tokRParen, "')'")
+		return c
+	}
+	if p.tok.kind != tokIdent {
+		p.unexpected("condition")
+	}
+
+	var t = p.tok
+	p.next()
+	if !t.quoted && p.tok.kind == tokLParen {
+		switch strings.ToLower(t.text) {
+		case "text", "textall":
+			p.next()
+			var s = p.string()
+			p.expect(tokRParen, "')'")
+			return TextSearch{Search: s, All: strings.EqualFold(t.text, "textall")}
+		case "nearest":
+			return p.nearest()
+		}
+		p.fail(t.pos, "unknown function %q", t.text)
+	}
+	if !t.quoted && (strings.EqualFold(t.text, "and") || strings.EqualFold(t.text, "or")) {
+		p.fail(t.pos, "unexpected '%s', expected condition", t.text)
+	}
+	return M{t.text: p.operation()}
+}
+
+// operation parses operator and its operand.
+func (p *parser) operation() interface{} {
+	var t = p.tok
+	if t.kind == tokOp {
+		p.next()
+		switch t.text {
+		case "=", "==":
+			return p.value()
+		case "!=", "<>":
+			return NotEqual(p.value())
+		case "<":
+			return LT(p.value())
+		case "<=":
+			return LTE(p.value())
+		case ">":
+			return GT(p.value())
+		case ">=":
+			return GTE(p.value())
+		case "~":
+			var re = p.expect(tokRegex, "regular expression")
+			return Regex(re.text, re.flags)
+		}
+	}
+	if t.kind != tokIdent || t.quoted {
+		p.unexpected("operator")
+	}
+
+	p.next()
+	switch strings.ToLower(t.text) {
+	case "in":
+		return In(p.list())
+	case "not":
+		switch {
+		case p.keyword("in"):
+			p.next()
+			return NotIn(p.list())
+		case p.keyword("exists"):
+			p.next()
+			return Exists(false)
+		}
+		p.unexpected("in or exists")
+	case "all":
+		return All(p.list())
+	case "size":
+		return Size(p.int())
+	case "exists":
+		return Exists(true)
+	case "prefix":
+		return Prefix(p.string())
+	case "iequal":
+		return IEqual(p.string())
+	case "elem":
+		p.expect(tokLParen, "'('")
+		p.elem++
+		var c = p.or()
+		p.elem--
+		p.expect(tokRParen, "')'")
+		return ElemMatch{c}
+	case "near":
+		p.expect(tokLParen, "'('")
+		var near GeoNear
+		near.Lng = p.float()
+		p.expect(tokComma, "','")
+		near.Lat = p.float()
+		if p.tok.kind == tokComma {
+			p.next()
+			near.MaxDistance = p.float()
+		}
+		p.expect(tokRParen, "')'")
+		return near
+	case "within":
+		return GeoWithin{p.shape()}
+	}
+	p.fail(t.pos, "unknown operator %s", t)
+	return nil
+}
+
+func (p *parser) shape() interface{} {
+	switch {
+	case p.keyword("box"):
+		p.next()
+		p.expect(tokLParen, "'('")
+		var box Box
+		for i, v := range []*float64{&box.MinLng, &box.MinLat, &box.MaxLng, &box.MaxLat} {
+			if i > 0 {
+				p.expect(tokComma, "','")
+			}
+			*v = p.float()
+		}
+		p.expect(tokRParen, "')'")
+		return box
+	case p.keyword("polygon"):
+		p.next()
+		p.expect(tokLParen, "'('")
+		var polygon Polygon
+		for {
+			p.expect(tokLParen, "'('")
+			var lng = p.float()
+			p.expect(tokComma, "','")
+			var lat = p.float()
+			p.expect(tokRParen, "')'")
+			polygon = append(polygon, [2]float64{lng, lat})
+			if p.tok.kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		p.expect(tokRParen, "')'")
+		return polygon
+	}
+	p.unexpected("box or polygon")
+	return nil
+}
+
+// nearest parses arguments of vector search.
+func (p *parser) nearest() interface{} {
+	p.expect(tokLParen, "'('")
+	var vs VectorSearch
+	vs.Field = p.expect(tokIdent, "field").text
+	p.expect(tokComma, "','")
+	p.expect(tokLBracket, "'['")
+	for p.tok.kind != tokRBracket {
+		if len(vs.Vector) > 0 {
+			p.expect(tokComma, "','")
+		}
+		vs.Vector = append(vs.Vector, float32(p.float()))
+	}
+	p.next()
+	p.expect(tokComma, "','")
+	vs.K = int(p.int())
+	p.expect(tokComma, "','")
+	var metric = p.expect(tokIdent, "metric")
+	switch m := VectorMetric(strings.ToLower(metric.text)); m {
+	case Cosine, Euclidean, DotProduct:
+		vs.Metric = m
+	default:
+		p.fail(metric.pos, "unknown metric %q", metric.text)
+	}
+	p.expect(tokRParen, "')'")
+	return vs
+}
+
+// list parses parenthesized list of values.
+func (p *parser) list() []interface{} {
+	p.expect(tokLParen, "'('")
+	var list = []interface{}{}
+	for p.tok.kind != tokRParen {
+		if len(list) > 0 {
+			p.expect(tokComma, "',' or ')'")
+		}
+		list = append(list, p.value())
+	}
+	p.next()
+	return list
+}
+
+func (p *parser) value() interface{} {
+	var t = p.tok
+	switch {
+	case t.kind == tokString:
+		p.next()
+		return t.text
+	case t.kind == tokNumber:
+		p.next()
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return i
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			p.fail(t.pos, "invalid number %s", t.text)
+		}
+		return f
+	case p.keyword("true"):
+		p.next()
+		return true
+	case p.keyword("false"):
+		p.next()
+		return false
+	case p.keyword("null"):
+		p.next()
+		return nil
+	case t.kind == tokLBracket:
+		p.next()
+		var a = []interface{}{}
+		for p.tok.kind != tokRBracket {
+			if len(a) > 0 {
+				p.expect(tokComma, "',' or ']'")
+			}
+			a = append(a, p.value())
+		}
+		p.next()
+		return a
+	case t.kind == tokLBrace:
+		p.next()
+		var m = map[string]interface{}{}
+		for n := 0; p.tok.kind != tokRBrace; n++ {
+			if n > 0 {
+				p.expect(tokComma, "',' or '}'")
+			}
+			var key = p.string()
+			p.expect(tokColon, "':'")
+			m[key] = p.value()
+		}
+		p.next()
+		return m
+	}
+	p.unexpected("value")
+	return nil
+}
+
+func (p *parser) string() string {
+	return p.expect(tokString, "string").text
+}
+
+func (p *parser) int() int64 {
+	var pos = p.tok.pos
+	i, ok := p.value().(int64)
+	if !ok {
+		p.fail(pos, "expected integer")
+	}
+	return i
+}
+
+func (p *parser) float() float64 {
+	var pos = p.tok.pos
+	switch v := p.value().(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	p.fail(pos, "expected number")
+	return 0
+}
@@ -1,20 +1,30 @@
 package redis
 
 import (
+	"bytes"
+	"sort"
 	"strconv"
 
 	"github.com/gocontrib/nosql/kv"
 )
 
 type bucket struct {
-	prefix string
-	keyID  []byte
-	tx     Tx
+	prefix   string
+	keyID    []byte
+	keyKeys  []byte // sorted set of bucket keys
+	keyReady []byte // marks that sorted set holds all keys
+	tx       Tx
+	sorted   map[string][][]byte // sorted keys of buckets scanned in transaction
 }
 
 const (
-	separator = "_"
+	// separator of bucket name and key, names never contain it, so keys of bucket
+	// do not overlap with keys of bucket which name is prefixed by its name
+	// (e.g. idx_users_name and idx_users_name_first)
+	separator = "\x00"
 	keyID     = "id"
+	keyKeys   = "keys"
+	keyReady  = "keys_ready"
 )
 
 func (b *bucket) LastID() (int64, error) {
@@ -44,12 +54,28 @@ func (b *bucket) get(key string) ([]byte, error) {
 
 func (b *bucket) Set(k []byte, v []byte) error {
 	k = []byte(b.prefix + string(k))
-	return debug.Err("set", b.tx.Set(k, v))
+	var err = b.tx.Set(k, v)
+	if err != nil {
+		return debug.Err("set", err)
+	}
+	delete(b.sorted, b.prefix)
+	if z, ok := b.tx.(SortedTx); ok {
+		return debug.Err("zadd", z.ZAdd(b.keyKeys, k))
+	}
+	return nil
 }
 
 func (b *bucket) Delete(k []byte) error {
 	k = []byte(b.prefix + string(k))
-	return debug.Err("delete", b.tx.Delete(k))
+	var err = b.tx.Delete(k)
+	if err != nil {
+		return debug.Err("delete", err)
+	}
+	delete(b.sorted, b.prefix)
+	if z, ok := b.tx.(SortedTx); ok {
+		return debug.Err("zrem", z.ZRem(b.keyKeys, k))
+	}
+	return nil
 }
 
 func (b *bucket) NextSequence() (string, error) {
@@ -63,3 +89,150 @@ func (b *bucket) NextSequence() (string, error) {
 func (b *bucket) Cursor() kv.Cursor {
 	return &cursor{bucket: b}
 }
+
+// ranger scans prefixed keys of bucket in order.
+type ranger interface {
+	scan(start []byte, count int, inclusive, reverse bool) ([][]byte, error)
+}
+
+// ranger returns ordered scanner of bucket keys.
+func (b *bucket) ranger() (ranger, error) {
+	if tx, ok := b.tx.(OrderedTx); ok {
+		return &scanRanger{tx, b.prefix}, nil
+	}
+	if tx, ok := b.tx.(SortedTx); ok {
+		var err = b.initRegistry(tx)
+		if err != nil {
+			return nil, err
+		}
+		return &registryRanger{tx, b.keyKeys}, nil
+	}
+	// SCAN command is not ordered, so all keys have to be sorted,
+	// they are scanned once per transaction until bucket is changed
+	if keys, ok := b.sorted[b.prefix]; ok {
+		return &sortedRanger{keys}, nil
+	}
+	keys, err := b.scanAll()
+	if err != nil {
+		return nil, err
+	}
+	b.sorted[b.prefix] = keys
+	return &sortedRanger{keys}, nil
+}
+
+// scanAll loads all keys of the bucket in ascending order.
+func (b *bucket) scanAll() ([][]byte, error) {
+	var all [][]byte
+	var next = 0
+	for {
+		n, keys, err := b.tx.Scan(b.prefix, next, keyRangeLimit, nil)
+		if err != nil {
+			return nil, debug.Err("scan", err)
+		}
+		all = append(all, b.own(keys)...)
+		if n == 0 {
+			break
+		}
+		next = n
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return bytes.Compare(all[i], all[j]) < 0
+	})
+	return all, nil
+}
+
+// own filters keys of the bucket out of scanned keys, so neither sorted keys
+// nor registry get keys of other buckets if backend matches keys loosely.
+func (b *bucket) own(keys [][]byte) [][]byte {
+	var list = keys[:0]
+	for _, k := range keys {
+		if bytes.HasPrefix(k, []byte(b.prefix)) {
+			list = append(list, k)
+		}
+	}
+	return list
+}
+
+// initRegistry registers keys written before sorted set of keys was maintained.
+func (b *bucket) initRegistry(tx SortedTx) error {
+	n, err := b.tx.Exists(b.keyReady)
+	if err != nil || n > 0 {
+		return err
+	}
+	var next = 0
+	for {
+		n, keys, err := b.tx.Scan(b.prefix, next, keyRangeLimit, nil)
+		if err != nil {
+			return debug.Err("scan", err)
+		}
+		keys = b.own(keys)
+		if len(keys) > 0 {
+			err = tx.ZAdd(b.keyKeys, keys...)
+			if err != nil {
+				return debug.Err("zadd", err)
+			}
+		}
+		if n == 0 {
+			break
+		}
+		next = n
+	}
+	return b.tx.Set(b.keyReady, []byte("1"))
+}
+
+type scanRanger struct {
+	tx     OrderedTx
+	prefix string
+}
+
+func (r *scanRanger) scan(start []byte, count int, inclusive, reverse bool) ([][]byte, error) {
+	if reverse {
+		return r.tx.RevScanFrom(r.prefix, start, count, inclusive)
+	}
+	if start == nil {
+		start = []byte(r.prefix)
+		inclusive = true
+	}
+	return r.tx.ScanFrom(r.prefix, start, count, inclusive)
+}
+
+type registryRanger struct {
+	tx  SortedTx
+	key []byte
+}
+
+func (r *registryRanger) scan(start []byte, count int, inclusive, reverse bool) ([][]byte, error) {
+	return r.tx.ZRangeByLex(r.key, start, count, inclusive, reverse)
+}
+
+type sortedRanger struct {
+	keys [][]byte
+}
+
+func (r *sortedRanger) scan(start []byte, count int, inclusive, reverse bool) ([][]byte, error) {
+	var keys [][]byte
+	if reverse {
+		var i = len(r.keys) - 1
+		if start != nil {
+			i = sort.Search(len(r.keys), func(i int) bool {
+				var c = bytes.Compare(r.keys[i], start)
+				return c > 0 || (!inclusive && c == 0)
+			}) - 1
+		}
+		for ; i >= 0 && len(keys) < count; i-- {
+			keys = append(keys, r.keys[i])
+		}
+		return keys, nil
+	}
+	var i = 0
+	if start != nil {
+		i = sort.Search(len(r.keys), func(i int) bool {
+			var c = bytes.Compare(r.keys[i], start)
+			return c > 0 || (inclusive && c == 0)
+		})
+	}
+	for ; i < len(r.keys) && len(keys) < count; i++ {
+		keys = append(keys, r.keys[i])
+	}
+	return keys, nil
+}
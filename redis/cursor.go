@@ -1,59 +1,85 @@
 package redis
 
 import (
-	"strconv"
-
 	"github.com/gocontrib/log"
 )
 
 const keyRangeLimit = 100
 
 type cursor struct {
-	bucket      *bucket
-	err         error
-	initialized bool
-	lastID      int64
-	keys        [][]byte
-	idx         int
-	current     int
-	next        int
+	bucket  *bucket
+	ranger  ranger
+	err     error
+	keys    [][]byte // current range of prefixed keys in scan direction
+	idx     int
+	last    bool   // whether current range is the last one
+	reverse bool   // whether keys are scanned in descending order
+	cur     []byte // current prefixed key
 }
 
-func (c *cursor) init() {
-	if c.initialized {
-		return
+// load loads range of keys starting from given prefixed key in current direction.
+func (c *cursor) load(start []byte, inclusive bool) {
+	if c.ranger == nil {
+		c.ranger, c.err = c.bucket.ranger()
+		if c.err != nil {
+			return
+		}
 	}
-	c.initialized = true
 
-	id, err := c.bucket.LastID()
+	keys, err := c.ranger.scan(start, keyRangeLimit, inclusive, c.reverse)
 	if err != nil {
 		c.err = err
+		debug.Err("scan", err)
 		return
 	}
-	c.lastID = id
 
-	c.scan(0, nil)
+	c.keys = keys
+	c.idx = 0
+	c.last = len(keys) < keyRangeLimit
 }
 
-func (c *cursor) scan(cursor int, last []byte) error {
-	if c.err != nil {
-		return c.err
+// more loads next range of keys, returns false if there are no more keys.
+func (c *cursor) more() bool {
+	if c.err != nil || c.last || len(c.keys) == 0 {
+		return false
 	}
+	c.load(c.keys[len(c.keys)-1], false)
+	return c.err == nil
+}
 
-	next, keys, err := c.bucket.tx.Scan(c.bucket.prefix, cursor, keyRangeLimit, last)
-
-	if err != nil {
-		c.err = err
-		debug.Err("scan", err)
-		return err
+// current returns current pair skipping keys deleted in the meantime.
+func (c *cursor) current() ([]byte, []byte) {
+	for c.err == nil {
+		if c.idx >= len(c.keys) {
+			if !c.more() {
+				c.cur = nil
+				return nil, nil
+			}
+			continue
+		}
+		c.cur = c.keys[c.idx]
+		k, v := c.seek(c.cur)
+		if k != nil {
+			return k, v
+		}
+		c.idx++
 	}
+	return nil, nil
+}
 
-	c.keys = keys
-	c.idx = 0
-	c.current = cursor
-	c.next = next
-
-	return nil
+// move moves the cursor by one key in given direction.
+func (c *cursor) move(reverse bool) ([]byte, []byte) {
+	if c.err != nil || c.cur == nil {
+		return nil, nil
+	}
+	if c.reverse != reverse {
+		// direction changed, reload keys from current one
+		c.reverse = reverse
+		c.load(c.cur, false)
+		return c.current()
+	}
+	c.idx++
+	return c.current()
 }
 
 func logKeys(keys [][]byte) {
@@ -64,69 +90,44 @@ func logKeys(keys [][]byte) {
 	log.Debug("ledis: keys %v", a)
 }
 
-func (c *cursor) makeKey(id int64) []byte {
-	return []byte(c.bucket.prefix + strconv.FormatInt(id, 10))
-}
-
 func (c *cursor) First() ([]byte, []byte) {
-	c.init()
-
-	if c.current != 0 {
-		c.scan(0, nil)
-	}
-
-	if c.err != nil {
-		return nil, nil
-	}
-
-	c.idx = 0
-
-	if c.idx < len(c.keys) {
-		return c.seek(c.keys[c.idx])
-	}
+	c.reverse = false
+	c.load(nil, true)
+	return c.current()
+}
 
-	return nil, nil
+func (c *cursor) Last() ([]byte, []byte) {
+	c.reverse = true
+	c.load(nil, true)
+	return c.current()
 }
 
 func (c *cursor) Next() ([]byte, []byte) {
-	var inc = 1
-	if !c.initialized {
-		inc = 0
-	}
-
-	c.init()
-
-	if c.err != nil {
-		return nil, nil
-	}
-
-	c.idx = c.idx + inc
-
-	if c.idx < len(c.keys) {
-		return c.seek(c.keys[c.idx])
+	if c.keys == nil && c.cur == nil {
+		return c.First()
 	}
+	return c.move(false)
+}
 
-	// get next range
-	if c.next > 0 && c.lastID > 0 && len(c.keys) > 0 {
-		c.scan(c.next, c.keys[c.idx-1])
-		if c.err != nil {
-			return nil, nil
-		}
-		if c.idx < len(c.keys) {
-			return c.seek(c.keys[c.idx])
-		}
+func (c *cursor) Prev() ([]byte, []byte) {
+	if c.keys == nil && c.cur == nil {
+		return c.Last()
 	}
-
-	return nil, nil
+	return c.move(true)
 }
 
+// Seek moves the cursor to given key or next one if key does not exist.
 func (c *cursor) Seek(k []byte) ([]byte, []byte) {
 	if k == nil || c.err != nil {
 		return nil, nil
 	}
+
 	// add prefix
 	k = []byte(c.bucket.prefix + string(k))
-	return c.seek(k)
+
+	c.reverse = false
+	c.load(k, true)
+	return c.current()
 }
 
 // key must be prefixed in seek function
@@ -18,3 +18,21 @@ type Tx interface {
 	Incr(k []byte) (int64, error)
 	Scan(prefix string, cursor int, count int, last []byte) (int, [][]byte, error)
 }
+
+// OrderedTx is implemented by backends able to scan keys in order
+// starting from given key (e.g. ledis).
+type OrderedTx interface {
+	ScanFrom(prefix string, start []byte, count int, inclusive bool) ([][]byte, error)
+	// RevScanFrom scans keys in descending order, nil start means the last key.
+	RevScanFrom(prefix string, start []byte, count int, inclusive bool) ([][]byte, error)
+}
+
+// SortedTx is implemented by backends with sorted sets (e.g. redis).
+// Keys of buckets are registered in sorted set to iterate them in order.
+type SortedTx interface {
+	ZAdd(key []byte, members ...[]byte) error
+	ZRem(key []byte, members ...[]byte) error
+	// ZRangeByLex returns members in lexicographical order starting from given member,
+	// nil start means the first member (the last one in reverse order).
+	ZRangeByLex(key []byte, start []byte, count int, inclusive, reverse bool) ([][]byte, error)
+}
@@ -1,6 +1,8 @@
 package redis
 
 import (
+	"strings"
+
 	"github.com/garyburd/redigo/redis"
 	"github.com/gocontrib/log"
 	"github.com/soveran/redisurl"
@@ -108,7 +110,7 @@ func (s *redisStore) Incr(k []byte) (int64, error) {
 }
 
 func (s *redisStore) Scan(prefix string, cursor int, count int, last []byte) (int, [][]byte, error) {
-	v, err := redis.Values(s.Do("SCAN", cursor, "MATCH", prefix+"*", "COUNT", count))
+	v, err := redis.Values(s.Do("SCAN", cursor, "MATCH", globEscape(prefix)+"*", "COUNT", count))
 	if err != nil {
 		return 0, nil, err
 	}
@@ -129,3 +131,69 @@ func (s *redisStore) Scan(prefix string, cursor int, count int, last []byte) (in
 	}
 	return next, keys, nil
 }
+
+// globEscape escapes special characters of MATCH pattern,
+// otherwise prefix "logs[1]" matches keys of bucket "logs1".
+func globEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(`*?[]\`, s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func (s *redisStore) ZAdd(key []byte, members ...[]byte) error {
+	var args = []interface{}{key}
+	for _, m := range members {
+		args = append(args, 0, m)
+	}
+	_, err := s.Do("ZADD", args...)
+	return err
+}
+
+func (s *redisStore) ZRem(key []byte, members ...[]byte) error {
+	var args = []interface{}{key}
+	for _, m := range members {
+		args = append(args, m)
+	}
+	_, err := s.Do("ZREM", args...)
+	return err
+}
+
+func (s *redisStore) ZRangeByLex(key []byte, start []byte, count int, inclusive, reverse bool) ([][]byte, error) {
+	var from interface{}
+	if start == nil {
+		from = "-"
+		if reverse {
+			from = "+"
+		}
+	} else if inclusive {
+		from = append([]byte("["), start...)
+	} else {
+		from = append([]byte("("), start...)
+	}
+
+	var v []interface{}
+	var err error
+	if reverse {
+		v, err = redis.Values(s.Do("ZREVRANGEBYLEX", key, from, "-", "LIMIT", 0, count))
+	} else {
+		v, err = redis.Values(s.Do("ZRANGEBYLEX", key, from, "+", "LIMIT", 0, count))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]byte
+	for _, m := range v {
+		b, err := redis.Bytes(m, nil)
+		if err != nil {
+			return nil, debug.Err("redis.Bytes", err)
+		}
+		keys = append(keys, b)
+	}
+	return keys, nil
+}
@@ -42,7 +42,7 @@ func (s *store) Begin(writable bool) (kv.Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &kvtx{tx}, nil
+	return &kvtx{tx: tx, sorted: make(map[string][][]byte)}, nil
 }
 
 func (s *store) Close() error {
@@ -51,7 +51,8 @@ func (s *store) Close() error {
 }
 
 type kvtx struct {
-	tx Tx
+	tx     Tx
+	sorted map[string][][]byte
 }
 
 func (t *kvtx) Commit() error {
@@ -66,7 +67,11 @@ func (t *kvtx) Bucket(name string, createIfNotExists bool) (kv.Bucket, error) {
 	var prefix = name + separator
 	return &bucket{
 		prefix: prefix,
-		keyID:  []byte("_" + prefix + keyID),
-		tx:     t.tx,
+		// metadata keys start with separator, so they are out of keys of all buckets
+		keyID:    []byte(separator + prefix + keyID),
+		keyKeys:  []byte(separator + prefix + keyKeys),
+		keyReady: []byte(separator + prefix + keyReady),
+		tx:       t.tx,
+		sorted:   t.sorted,
 	}, nil
 }
@@ -44,6 +44,29 @@ type Result interface {
 	Sort(...string) Result
 	// Cursor executes query and returns cursor capable of going over all the results.
 	Cursor() (Cursor, error)
+	// Explain describes how data store executes query without reading results.
+	Explain() (Plan, error)
+}
+
+// Plan describes execution of query, see Result.Explain.
+type Plan struct {
+	// Access is path to documents in KV stores: "lookup" (keys found in indexes),
+	// "index" (walk of index of sort field), "scan" (full scan of collection) or "none" (filter matches nothing).
+	Access string
+	// Indexes are names of index buckets used by KV stores.
+	Indexes []string
+	// Sort is order of results in KV stores: "none", "keys" (order of ids), "index" (walk of index of sort field),
+	// "memory" (sorting of found documents), "rank" (relevance of text search or distance of vector search)
+	// or "distance" (distance of near condition).
+	Sort string
+	// Filter is residual filter applied to documents found by access path in KV stores.
+	Filter interface{}
+	// Statement is native query, SQL of postgresql or JSON of mongo query.
+	Statement string
+	// Args are parameters of statement.
+	Args []interface{}
+	// Explain is output of native explain of data store.
+	Explain string
 }
 
 // Cursor API
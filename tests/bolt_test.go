@@ -33,6 +33,126 @@ func TestBoltStore_Filters(t *testing.T) {
 	testFilters(t, store)
 }
 
+func TestBoltStore_SortLimit(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testSortLimit(t, store)
+}
+
+func TestBoltStore_SortSpill(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testSortSpill(t, store)
+}
+
+func TestBoltStore_SortByID(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testSortByID(t, store)
+}
+
+func TestBoltStore_IDGenerators(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testIDGenerators(t, store)
+}
+
+func TestBoltStore_NestedFields(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testNestedFields(t, store)
+}
+
+func TestBoltStore_ArrayOperators(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testArrayOperators(t, store)
+}
+
+func TestBoltStore_StringOperators(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testStringOperators(t, store)
+}
+
+func TestBoltStore_NullSemantics(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testNullSemantics(t, store)
+}
+
+func TestBoltStore_TextSearch(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testTextSearch(t, store)
+}
+
+func TestBoltStore_GeoQueries(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testGeoQueries(t, store)
+}
+
+func TestBoltStore_VectorSearch(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testVectorSearch(t, store)
+}
+
+func TestBoltStore_Builder(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testBuilder(t, store)
+}
+
+func TestBoltStore_Example(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testExample(t, store)
+}
+
+func TestBoltStore_Explain(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testExplain(t, store)
+}
+
+func TestBoltStore_TypedCompare(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testTypedCompare(t, store)
+}
+
+func TestBoltStore_SortByTime(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testSortByTime(t, store)
+}
+
+func TestBoltStore_SiblingIndexes(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testSiblingIndexes(t, store)
+}
+
+func TestBoltStore_SiblingCollections(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testSiblingCollections(t, store)
+}
+
+func TestBoltStore_Index(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testIndex(t, store)
+}
+
+func TestBoltStore_Reindex(t *testing.T) {
+	var store = makeBoltStore()
+	defer store.Close()
+	testReindex(t, store)
+}
+
 func BenchmarkBoltStore_Insert(b *testing.B) {
 	var store = makeBoltStore()
 	defer store.Close()
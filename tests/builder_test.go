@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocontrib/nosql/q"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	assert := assert.New(t)
+
+	var cases = []struct {
+		cond   q.Cond
+		filter interface{}
+	}{
+		{q.Field("age").Gte(20), q.M{"age": q.GTE(20)}},
+		{
+			q.Field("age").Gte(20).And(q.Field("name").In("bob", "rob")),
+			q.And{q.M{"age": q.GTE(20)}, q.M{"name": q.In{"bob", "rob"}}},
+		},
+		{
+			q.Field("a").Eq(1).And(q.Field("b").Eq(2)).And(q.Field("c").Eq(3)),
+			q.And{q.M{"a": 1}, q.M{"b": 2}, q.M{"c": 3}},
+		},
+		{
+			q.Field("a").Eq(1).Or(q.Field("b").IsNull()).And(q.Field("c").Ne("x").Not().Not().Not()),
+			q.And{q.Or{q.M{"a": 1}, q.M{"b": nil}}, q.Not{Condition: q.M{"c": q.NotEqual("x")}}},
+		},
+		{q.AnyOf(q.Field("a").Exists(), q.Field("a").Size(0)).Not(), q.Not{Condition: q.Or{q.M{"a": q.Exists(true)}, q.M{"a": q.Size(0)}}}},
+		{q.Field("age").Between(20, 30), q.And{q.M{"age": q.GTE(20)}, q.M{"age": q.LTE(30)}}},
+		{
+			q.Field("scores").ElemMatch(q.Elem().Gte(5).And(q.Elem().Lt(10))),
+			q.M{"scores": q.ElemMatch{Condition: q.And{q.GTE(5), q.LT(10)}}},
+		},
+		{
+			q.Field("comments").ElemMatch(q.Field("author").Eq("bob")),
+			q.M{"comments": q.ElemMatch{Condition: q.M{"author": "bob"}}},
+		},
+		{q.Where(q.Text("fox")).And(q.Field("title").Prefix("q")), q.And{q.Text("fox"), q.M{"title": q.Prefix("q")}}},
+		{q.Field("loc").Near(13.4, 52.5, 100), q.M{"loc": q.Near(13.4, 52.5, 100)}},
+	}
+
+	for _, c := range cases {
+		filter, err := c.cond.Build()
+		ok(t, "build", err)
+		assert.Equal(c.filter, filter)
+	}
+
+	var name = "bob"
+	filter, err := q.Field("name").Eq(&name).Build()
+	ok(t, "build", err)
+	assert.Equal(q.M{"name": "bob"}, filter)
+}
+
+func TestBuilderErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, c := range []q.Cond{
+		{},
+		q.Field("").Eq(1),
+		q.Field("a..b").Eq(1),
+		q.Field("$where").Eq(1),
+		q.Field("age").Gt(nil),
+		q.Field("age").Gt(true),
+		q.Field("age").Eq(struct{}{}),
+		q.Field("tags").In([]string{"a"}),
+		q.Field("tags").Size(-1),
+		q.Field("tags").All(),
+		q.Field("name").Regex("(", ""),
+		q.Field("name").Regex("a", "g"),
+		q.Field("loc").Near(200, 0, 1),
+		q.Field("loc").Within(q.Polygon{{1, 2}, {3, 4}}),
+		q.Field("loc").Within(q.Box{MinLng: 3, MinLat: 2, MaxLng: 1, MaxLat: 4}),
+		q.Field("a").Eq(1).And(q.Field("b").Lt(nil)).Or(q.Field("c").Eq(1)),
+		q.Field("a").Eq(1).Not().And(q.Cond{}),
+		q.Elem().Gt(1),
+		q.Field("scores").ElemMatch(q.Elem().Gt(nil)),
+		q.Where(q.M{}),
+		q.AllOf(),
+	} {
+		_, err := c.Build()
+		assert.Error(err)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocontrib/nosql/q"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ExampleBase struct {
+	Kind string `json:"kind"`
+}
+
+type exampleDoc struct {
+	ExampleBase
+	ID      string            `bson:"_id"`
+	Title   string            `bson:"title"`
+	Rating  *int              `json:"rating,omitempty"`
+	Tags    []string          `json:"tags"`
+	Address Address           `json:"address"`
+	Meta    map[string]string `json:"meta"`
+	Skipped string            `json:"-"`
+	hidden  string
+}
+
+func TestExample(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(q.M{"name": "bob", "age": int64(20)}, q.Example(&User{Name: "bob", Age: 20}))
+	assert.Equal(q.M{"id": "1"}, q.Example(User{ID: "1"}))
+
+	var zero = 0
+	var doc = exampleDoc{
+		ExampleBase: ExampleBase{Kind: "post"},
+		Title:       "x",
+		Rating:      &zero,
+		Tags:        []string{"a", "b"},
+		Address:     Address{City: "Berlin"},
+		Meta:        map[string]string{"a": "b"},
+		Skipped:     "x",
+		hidden:      "x",
+	}
+	assert.Equal(q.M{
+		"kind":         "post",
+		"title":        "x",
+		"rating":       0,
+		"tags":         q.All{"a", "b"},
+		"address.city": "Berlin",
+	}, q.Example(&doc))
+
+	assert.Equal(q.M{"kind": "post", "title": "x", "rating": 0, "tags": q.All{"a", "b"}},
+		q.Example(&doc, q.SkipNested()))
+
+	assert.Equal(q.M{"address.city": "", "address.zip": "", "address.floor": int64(0), "rating": nil},
+		q.Example(&exampleDoc{}, q.IncludeZero("address", "rating")))
+
+	assert.Equal(q.M{"kind": "", "title": "", "rating": nil, "address.city": "", "address.zip": "", "address.floor": int64(0)},
+		q.Example(&exampleDoc{}, q.IncludeZero()))
+
+	assert.Equal(q.M{}, q.Example(&User{}))
+	assert.Panics(func() { q.Example(42) })
+}
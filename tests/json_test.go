@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocontrib/nosql/q"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var cases = []struct {
+		json   string
+		filter interface{}
+	}{
+		{`{"age": {"$gte": 20}}`, q.M{"age": q.GTE(int64(20))}},
+		{`{"name": "bob", "age": 20.5}`, q.M{"name": "bob", "age": 20.5}},
+		{`{"age": {"$gte": 20, "$lt": 30}}`, q.And{q.M{"age": q.GTE(int64(20))}, q.M{"age": q.LT(int64(30))}}},
+		{
+			`{"age": {"$gt": 1}, "$or": [{"name": {"$in": ["bob", "rob"]}}, {"email": null}]}`,
+			q.And{q.M{"age": q.GT(int64(1))}, q.Or{q.M{"name": q.In{"bob", "rob"}}, q.M{"email": nil}}},
+		},
+		{`{"$nor": [{"a": 1}]}`, q.Not{Condition: q.M{"a": int64(1)}}},
+		{`{"$nor": [{"a": 1}, {"b": 2}]}`, q.Not{Condition: q.Or{q.M{"a": int64(1)}, q.M{"b": int64(2)}}}},
+		{`{"age": {"$not": {"$gt": 5}}}`, q.Not{Condition: q.M{"age": q.GT(int64(5))}}},
+		{`{"tags": {"$all": ["a"], "$size": 1}}`, q.And{q.M{"tags": q.All{"a"}}, q.M{"tags": q.Size(1)}}},
+		{`{"name": {"$regex": "^b.b$", "$options": "i"}}`, q.M{"name": q.Regex("^b.b$", "i")}},
+		{`{"name": {"$regex": "^b\\.b"}}`, q.M{"name": q.Prefix("b.b")}},
+		{`{"name": {"$regex": "^bob$", "$options": "i"}}`, q.M{"name": q.IEqual("bob")}},
+		{`{"address": {"city": "x", "zip": 1}}`, q.M{"address": map[string]interface{}{"city": "x", "zip": int64(1)}}},
+		{
+			`{"comments": {"$elemMatch": {"author": "bob", "likes": {"$gt": 1}}}}`,
+			q.M{"comments": q.ElemMatch{Condition: q.M{"author": "bob", "likes": q.GT(int64(1))}}},
+		},
+		{`{"scores": {"$elemMatch": {"$gte": 5, "$lt": 10}}}`, q.M{"scores": q.ElemMatch{Condition: q.And{q.GTE(int64(5)), q.LT(int64(10))}}}},
+		{`{"$text": {"$search": "\"quick\" \"fox\""}}`, q.TextAll("quick fox")},
+		{`{"$text": {"$search": "quick fox", "$language": "english"}}`, q.Text("quick fox")},
+		{
+			`{"location": {"$near": {"$geometry": {"type": "Point", "coordinates": [13.4, 52.5]}, "$maxDistance": 500}}}`,
+			q.M{"location": q.Near(13.4, 52.5, 500)},
+		},
+		{
+			`{"location": {"$geoWithin": {"$geometry": {"type": "Polygon", "coordinates": [[[1, 2], [3, 4], [5, 0], [1, 2]]]}}}}`,
+			q.M{"location": q.Within(q.Polygon{{1, 2}, {3, 4}, {5, 0}})},
+		},
+		{`{"location": {"$geoWithin": {"$box": [[1, 2], [3, 4]]}}}`, q.M{"location": q.Within(q.Box{MinLng: 1, MinLat: 2, MaxLng: 3, MaxLat: 4})}},
+		{
+			`{"location": {"$geoWithin": {"$geometry": {"type": "Polygon", "coordinates": [[[1, 2], [3, 2], [3, 4], [1, 4], [1, 2]]]}}}}`,
+			q.M{"location": q.Within(q.Box{MinLng: 1, MinLat: 2, MaxLng: 3, MaxLat: 4})},
+		},
+		{
+			`{"$vectorSearch": {"path": "vector", "queryVector": [1, 0.5], "limit": 3, "metric": "cosine"}}`,
+			q.NearestVector("vector", []float32{1, 0.5}, 3, q.Cosine),
+		},
+	}
+
+	for _, c := range cases {
+		filter, err := q.FromJSON([]byte(c.json))
+		ok(t, "from json "+c.json, err)
+		assert.Equal(c.filter, filter, c.json)
+
+		// round trip
+		data, err := q.ToJSON(c.filter)
+		ok(t, "to json", err)
+		filter, err = q.FromJSON(data)
+		ok(t, "from json "+string(data), err)
+		assert.Equal(c.filter, filter, string(data))
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	var cases = []struct {
+		json string
+		path string
+	}{
+		{`[1]`, ""},
+		{`{}`, ""},
+		{`{"a": 1} {}`, ""},
+		{`{"$where": "x"}`, "$where"},
+		{`{"age": {"$gte": 20, "$foo": 1}}`, "age.$foo"},
+		{`{"age": {"$gte": [1]}}`, "age.$gte"},
+		{`{"age": {"$gte": 1, "x": 1}}`, "age"},
+		{`{"name": {"$in": "bob"}}`, "name.$in"},
+		{`{"tags": {"$size": -1}}`, "tags.$size"},
+		{`{"a": {"$exists": 1}}`, "a.$exists"},
+		{`{"$or": [{"a": 1}, {"b": {"$regex": "("}}]}`, "$or[1].b.$regex"},
+		{`{"a": {"$regex": "x", "$options": "g"}}`, "a.$options"},
+		{`{"$and": []}`, "$and"},
+		{`{"$text": {"$search": "x", "$caseSensitive": true}}`, "$text.$caseSensitive"},
+		{`{"loc": {"$near": {"$geometry": {"type": "Point", "coordinates": [1]}}}}`, "loc.$near.$geometry.coordinates"},
+		{`{"$vectorSearch": {"path": "v", "queryVector": [1], "limit": 1, "metric": "l1"}}`, "$vectorSearch.metric"},
+	}
+
+	for _, c := range cases {
+		_, err := q.FromJSON([]byte(c.json))
+		if !assert.Error(err, c.json) {
+			continue
+		}
+		e, isJSONError := err.(*q.JSONError)
+		assert.True(isJSONError, c.json)
+		if isJSONError {
+			assert.Equal(c.path, e.Path, c.json+": "+e.Error())
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := q.ToJSON(q.And{q.M{"age": q.GTE(20)}, q.Not{Condition: q.M{"name": q.In{"bob"}}}})
+	ok(t, "to json", err)
+	assert.JSONEq(`{"$and": [{"age": {"$gte": 20}}, {"$nor": [{"name": {"$in": ["bob"]}}]}]}`, string(data))
+
+	_, err = q.ToJSON(42)
+	assert.Error(err)
+
+	// the same document is query of mongo store
+	data, err = q.ToJSON(q.M{"location": q.Within(q.Box{MinLng: 1, MinLat: 2, MaxLng: 3, MaxLat: 4})})
+	ok(t, "to json", err)
+	assert.JSONEq(`{"location": {"$geoWithin": {"$geometry": {"type": "Polygon", "coordinates": [[[1, 2], [3, 2], [3, 4], [1, 4], [1, 2]]]}}}}`, string(data))
+
+	doc, err := q.ToDocument(q.M{"scores": q.ElemMatch{Condition: q.And{q.GT(1), q.GT(2), q.LT(5)}}})
+	ok(t, "to document", err)
+	assert.Equal(map[string]interface{}{"scores": map[string]interface{}{"$elemMatch": map[string]interface{}{"$and": []interface{}{
+		map[string]interface{}{"$gt": 1}, map[string]interface{}{"$gt": 2}, map[string]interface{}{"$lt": 5},
+	}}}}, doc)
+}
@@ -33,6 +33,126 @@ func TestLedisStore_Filters(t *testing.T) {
 	testFilters(t, store)
 }
 
+func TestLedisStore_SortLimit(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testSortLimit(t, store)
+}
+
+func TestLedisStore_SortSpill(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testSortSpill(t, store)
+}
+
+func TestLedisStore_SortByID(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testSortByID(t, store)
+}
+
+func TestLedisStore_IDGenerators(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testIDGenerators(t, store)
+}
+
+func TestLedisStore_NestedFields(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testNestedFields(t, store)
+}
+
+func TestLedisStore_ArrayOperators(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testArrayOperators(t, store)
+}
+
+func TestLedisStore_StringOperators(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testStringOperators(t, store)
+}
+
+func TestLedisStore_NullSemantics(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testNullSemantics(t, store)
+}
+
+func TestLedisStore_TextSearch(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testTextSearch(t, store)
+}
+
+func TestLedisStore_GeoQueries(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testGeoQueries(t, store)
+}
+
+func TestLedisStore_VectorSearch(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testVectorSearch(t, store)
+}
+
+func TestLedisStore_Builder(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testBuilder(t, store)
+}
+
+func TestLedisStore_Example(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testExample(t, store)
+}
+
+func TestLedisStore_Explain(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testExplain(t, store)
+}
+
+func TestLedisStore_TypedCompare(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testTypedCompare(t, store)
+}
+
+func TestLedisStore_SortByTime(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testSortByTime(t, store)
+}
+
+func TestLedisStore_SiblingIndexes(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testSiblingIndexes(t, store)
+}
+
+func TestLedisStore_SiblingCollections(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testSiblingCollections(t, store)
+}
+
+func TestLedisStore_Index(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testIndex(t, store)
+}
+
+func TestLedisStore_Reindex(t *testing.T) {
+	var store = makeLedisStore()
+	defer store.Close()
+	testReindex(t, store)
+}
+
 func BenchmarkLedisStore_Insert(b *testing.B) {
 	var store = makeLedisStore()
 	defer store.Close()
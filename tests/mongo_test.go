@@ -33,6 +33,108 @@ func TestMongoStore_Filters(t *testing.T) {
 	testFilters(t, store)
 }
 
+func TestMongoStore_SortLimit(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testSortLimit(t, store)
+}
+
+func TestMongoStore_IDGenerators(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testIDGenerators(t, store)
+}
+
+func TestMongoStore_NestedFields(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testNestedFields(t, store)
+}
+
+func TestMongoStore_ArrayOperators(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testArrayOperators(t, store)
+}
+
+func TestMongoStore_StringOperators(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testStringOperators(t, store)
+}
+
+func TestMongoStore_NullSemantics(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testNullSemantics(t, store)
+}
+
+func TestMongoStore_TextSearch(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testTextSearch(t, store)
+}
+
+func TestMongoStore_GeoQueries(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testGeoQueries(t, store)
+}
+
+func TestMongoStore_VectorSearch(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testVectorSearch(t, store)
+}
+
+func TestMongoStore_Builder(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testBuilder(t, store)
+}
+
+func TestMongoStore_Example(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testExample(t, store)
+}
+
+func TestMongoStore_Explain(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testExplain(t, store)
+}
+
+func TestMongoStore_TypedCompare(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testTypedCompare(t, store)
+}
+
+func TestMongoStore_SortByTime(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testSortByTime(t, store)
+}
+
+func TestMongoStore_SiblingIndexes(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testSiblingIndexes(t, store)
+}
+
+func TestMongoStore_SiblingCollections(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testSiblingCollections(t, store)
+}
+
+func TestMongoStore_Index(t *testing.T) {
+	var store = makeMongoStore()
+	defer store.Close()
+	testIndex(t, store)
+}
+
 func BenchmarkMongoStore_Insert(b *testing.B) {
 	var store = makeMongoStore()
 	defer store.Close()
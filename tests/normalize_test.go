@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocontrib/nosql/q"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+
+	var day = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var cases = []struct {
+		filter   interface{}
+		expected interface{}
+	}{
+		{q.M{"name": "bob"}, q.M{"name": "bob"}},
+		{q.M{"name": q.In{"bob"}}, q.M{"name": "bob"}},
+		{q.M{"name": q.NotIn{"bob"}}, q.M{"name": q.NotEqual("bob")}},
+		{q.M{"name": q.In{"bob", "rob", "bob"}}, q.M{"name": q.In{"bob", "rob"}}},
+		{
+			q.And{q.M{"a": 1}, q.And{q.M{"b": 2}, q.And{q.M{"c": 3}}}},
+			q.M{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			q.Or{q.M{"a": 1}, q.Or{q.M{"b": 2}, q.Or{q.M{"c": 3}}}},
+			q.Or{q.M{"a": 1}, q.M{"b": 2}, q.M{"c": 3}},
+		},
+		{q.Not{Condition: q.Not{Condition: q.M{"a": 1}}}, q.M{"a": 1}},
+		{
+			q.Not{Condition: q.And{q.M{"a": 1}, q.M{"b": q.In{1, 2}}}},
+			q.Or{q.M{"a": q.NotEqual(1)}, q.M{"b": q.NotIn{1, 2}}},
+		},
+		{
+			q.Not{Condition: q.Or{q.M{"a": q.NotEqual(1)}, q.M{"b": q.Exists(true)}}},
+			q.M{"a": 1, "b": q.Exists(false)},
+		},
+		{q.Not{Condition: q.M{"a": 1, "b": nil}}, q.Or{q.M{"a": q.NotEqual(1)}, q.M{"b": q.NotEqual(nil)}}},
+		{q.Not{Condition: q.M{"a": q.GT(5)}}, q.Not{Condition: q.M{"a": q.GT(5)}}},
+		{
+			q.Not{Condition: q.Or{q.M{"name": q.In{"bob"}}, q.M{"age": q.LT(20)}}},
+			q.And{q.M{"name": q.NotEqual("bob")}, q.Not{Condition: q.M{"age": q.LT(20)}}},
+		},
+		{
+			q.And{q.M{"age": q.GTE(20)}, q.M{"age": q.GT(25)}, q.M{"age": q.LT(40)}, q.M{"age": q.LTE(30.5)}},
+			q.And{q.M{"age": q.GT(25)}, q.M{"age": q.LTE(30.5)}},
+		},
+		{q.And{q.M{"age": q.GTE(20)}, q.M{"age": q.LTE(20)}}, q.And{q.M{"age": q.GTE(20)}, q.M{"age": q.LTE(20)}}},
+		{q.And{q.M{"age": q.In{10, 20, 30}}, q.M{"age": q.GT(15)}}, q.And{q.M{"age": q.In{10, 20, 30}}, q.M{"age": q.GT(15)}}},
+		{
+			q.And{q.M{"age": q.In{10, 20, 30}}, q.M{"age": q.In{20, 30.0, 40}}},
+			q.And{q.M{"age": q.In{10, 20, 30}}, q.M{"age": q.In{20, 30.0, 40}}},
+		},
+		{q.And{q.M{"age": q.In{10, 20}}, q.M{"age": q.NotEqual(10)}}, q.And{q.M{"age": 20}, q.M{"age": q.NotEqual(10)}}},
+		{q.And{q.M{"age": 20}, q.M{"age": q.In{20, 30}}, q.M{"age": q.GT(10)}}, q.M{"age": 20}},
+		{q.And{q.M{"age": 20}, q.M{"age": q.GT(10)}, q.M{"age": q.NotEqual(30)}}, q.And{q.M{"age": 20}, q.M{"age": q.NotEqual(30)}}},
+		// arrays may hold both values or values in both ranges
+		{q.And{q.M{"tags": "a"}, q.M{"tags": "b"}}, q.And{q.M{"tags": "a"}, q.M{"tags": "b"}}},
+		{q.And{q.M{"scores": q.GT(5)}, q.M{"scores": q.LT(3)}}, q.And{q.M{"scores": q.GT(5)}, q.M{"scores": q.LT(3)}}},
+		{q.And{q.M{"scores": q.GT(5)}, q.M{"scores": q.LTE(5)}}, q.And{q.M{"scores": q.GT(5)}, q.M{"scores": q.LTE(5)}}},
+		{q.And{q.M{"a": nil}, q.M{"a": q.GT(1)}}, q.And{q.M{"a": nil}, q.M{"a": q.GT(1)}}},
+		{q.And{q.M{"at": q.GT(day)}, q.M{"at": q.LT(day.Add(time.Hour))}}, q.And{q.M{"at": q.GT(day)}, q.M{"at": q.LT(day.Add(time.Hour))}}},
+		{q.And{q.M{"a": q.GT(1)}, q.M{"a": q.NotEqual(0)}, q.M{"a": q.NotEqual(5)}}, q.And{q.M{"a": q.GT(1)}, q.M{"a": q.NotIn{0, 5}}}},
+		{q.And{q.M{"a": q.GT(1)}, q.M{"a": q.GT("x")}}, q.And{q.M{"a": q.GT(1)}, q.M{"a": q.GT("x")}}},
+		{q.And{q.M{"a": q.Exists(true)}, q.M{"a": q.GT(1)}}, q.M{"a": q.GT(1)}},
+		{q.And{q.M{"a": q.Exists(false)}, q.M{"a": nil}}, q.M{"a": q.Exists(false)}},
+		{q.And{q.M{"a": q.Exists(true)}, q.M{"a": q.NotEqual(1)}}, q.And{q.M{"a": q.NotEqual(1)}, q.M{"a": q.Exists(true)}}},
+		{q.And{q.M{"tags": q.All{"a"}}, q.M{"tags": q.Size(1)}}, q.And{q.M{"tags": q.All{"a"}}, q.M{"tags": q.Size(1)}}},
+		{q.Or{q.M{"a": 1}, q.Not{Condition: q.M{"a": q.NotIn{}}}}, q.M{"a": 1}},
+		{q.And{q.M{"a": 1}, q.M{"b": q.NotIn{}}}, q.M{"a": 1}},
+		{q.And{q.Text("fox"), q.M{"a": q.In{1}}}, q.And{q.M{"a": 1}, q.Text("fox")}},
+		// empty M matches all documents
+		{q.And{q.M{"a": 1}, q.M{}}, q.M{"a": 1}},
+		{q.Or{q.M{"a": 1}, q.Not{Condition: q.M{}}}, q.M{"a": 1}},
+	}
+
+	for _, c := range cases {
+		filter, ok := q.Normalize(c.filter)
+		assert.True(ok, q.Format(c.filter))
+		assert.Equal(c.expected, filter, q.Format(c.filter))
+	}
+
+	var contradictions = []interface{}{
+		q.And{q.M{"a": 1}, q.M{"a": q.NotEqual(1)}},
+		q.And{q.M{"a": q.In{1, 2}}, q.M{"a": q.NotIn{1, 2}}},
+		q.And{q.M{"a": q.In{1, 2}}, q.M{"a": q.NotEqual(1)}, q.M{"a": q.NotEqual(2.0)}},
+		q.And{q.M{"a": q.Exists(false)}, q.M{"a": 1}},
+		q.And{q.M{"a": q.Exists(false)}, q.M{"a": q.Exists(true)}},
+		q.And{q.M{"a": q.Exists(false)}, q.M{"a": q.GT(1)}},
+		q.M{"a": q.In{}},
+		q.Or{q.And{q.M{"a": 1}, q.M{"a": q.NotEqual(1)}}, q.M{"b": q.In{}}},
+		q.Not{Condition: q.M{"a": q.NotIn{}}},
+		q.Not{Condition: q.M{}},
+		q.And{q.M{"a": 1}, q.Not{Condition: q.M{}}},
+	}
+	for _, c := range contradictions {
+		filter, ok := q.Normalize(c)
+		assert.False(ok, q.Format(c))
+		assert.Nil(filter)
+	}
+
+	filter, ok := q.Normalize(q.M{"a": q.NotIn{}})
+	assert.True(ok)
+	assert.Nil(filter)
+
+	filter, ok = q.Normalize(q.Or{q.M{"a": 1}, q.M{}})
+	assert.True(ok)
+	assert.Nil(filter)
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/gocontrib/nosql/q"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	var cases = []struct {
+		expr   string
+		filter interface{}
+	}{
+		{`age >= 20`, q.M{"age": q.GTE(int64(20))}},
+		{
+			`age >= 20 and name in ("bob","rob") and not email = "x"`,
+			q.And{q.M{"age": q.GTE(int64(20))}, q.M{"name": q.In{"bob", "rob"}}, q.Not{Condition: q.M{"email": "x"}}},
+		},
+		{
+			`a = 1 or b != 'two' and (c < 1.5 or d = null)`,
+			q.Or{q.M{"a": int64(1)}, q.And{q.M{"b": q.NotEqual("two")}, q.Or{q.M{"c": q.LT(1.5)}, q.M{"d": nil}}}},
+		},
+		{`NOT (a > -1 AND b <= 2)`, q.Not{Condition: q.And{q.M{"a": q.GT(int64(-1))}, q.M{"b": q.LTE(int64(2))}}}},
+		{`address.city not in ("x") and zip exists`, q.And{q.M{"address.city": q.NotIn{"x"}}, q.M{"zip": q.Exists(true)}}},
+		{`color not exists`, q.M{"color": q.Exists(false)}},
+		{`name ~ /^b\/o$/i`, q.M{"name": q.Regex("^b/o$", "i")}},
+		{`name prefix "b" or name iequal "BOB"`, q.Or{q.M{"name": q.Prefix("b")}, q.M{"name": q.IEqual("BOB")}}},
+		{`tags all ("a", "b") and tags size 2`, q.And{q.M{"tags": q.All{"a", "b"}}, q.M{"tags": q.Size(2)}}},
+		{
+			`comments elem (author = "bob" and likes > 1)`,
+			q.M{"comments": q.ElemMatch{Condition: q.And{q.M{"author": "bob"}, q.M{"likes": q.GT(int64(1))}}}},
+		},
+		{`scores elem (>= 5 and < 10)`, q.M{"scores": q.ElemMatch{Condition: q.And{q.GTE(int64(5)), q.LT(int64(10))}}}},
+		{`scores elem (in (1, 2))`, q.M{"scores": q.ElemMatch{Condition: q.In{int64(1), int64(2)}}}},
+		{`text("quick fox") and textall("lazy dog")`, q.And{q.Text("quick fox"), q.TextAll("lazy dog")}},
+		{`location near (13.4, 52.5, 500)`, q.M{"location": q.Near(13.4, 52.5, 500)}},
+		{`location within box(1, 2, 3, 4)`, q.M{"location": q.Within(q.Box{MinLng: 1, MinLat: 2, MaxLng: 3, MaxLat: 4})}},
+		{`location within polygon((1, 2), (3, 4), (5, 0))`, q.M{"location": q.Within(q.Polygon{{1, 2}, {3, 4}, {5, 0}})}},
+		{`nearest(vector, [1, 0.5], 3, cosine)`, q.NearestVector("vector", []float32{1, 0.5}, 3, q.Cosine)},
+		{"`and` = true and `size` = false", q.And{q.M{"and": true}, q.M{"size": false}}},
+		{`tags = ["go", 1] and tags != []`, q.And{q.M{"tags": []interface{}{"go", int64(1)}}, q.M{"tags": q.NotEqual([]interface{}{})}}},
+		{`tags in (["go"], ["rust", ["x"]])`, q.M{"tags": q.In{[]interface{}{"go"}, []interface{}{"rust", []interface{}{"x"}}}}},
+		{
+			`comments = {"author": "bob", "tags": ["a"]}`,
+			q.M{"comments": map[string]interface{}{"author": "bob", "tags": []interface{}{"a"}}},
+		},
+	}
+
+	for _, c := range cases {
+		filter, err := q.Parse(c.expr)
+		ok(t, "parse "+c.expr, err)
+		assert.Equal(c.filter, filter, c.expr)
+
+		// round trip
+		filter, err = q.Parse(q.Format(c.filter))
+		ok(t, "parse "+q.Format(c.filter), err)
+		assert.Equal(c.filter, filter, q.Format(c.filter))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	var cases = []struct {
+		expr   string
+		offset int
+	}{
+		{``, 0},
+		{`age >=`, 6},
+		{`age >= 20 and`, 13},
+		{`age 20`, 4},
+		{`(age > 1`, 8},
+		{`name = "bob`, 7},
+		{`name ~ /b/x`, 10},
+		{`name in ("a" "b")`, 13},
+		{`age > 1 age < 2`, 8},
+		{`tags size 1.5`, 10},
+		{`nearest(v, [1], 1, manhattan)`, 19},
+		{`foo(1)`, 0},
+		{`tags = [1 2]`, 10},
+		{`tags = [1,`, 10},
+		{`a = {b: 1}`, 5},
+		{`a = {"b" 1}`, 9},
+	}
+
+	for _, c := range cases {
+		_, err := q.Parse(c.expr)
+		if !assert.Error(err, c.expr) {
+			continue
+		}
+		e, isParseError := err.(*q.ParseError)
+		assert.True(isParseError, c.expr)
+		if isParseError {
+			assert.Equal(c.offset, e.Offset, c.expr+": "+e.Error())
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(`age >= 20 and name in ("bob", "rob") and not email = "x"`,
+		q.Format(q.And{q.M{"age": q.GTE(20)}, q.M{"name": q.In{"bob", "rob"}}, q.Not{Condition: q.M{"email": "x"}}}))
+	assert.Equal(`(a = 1 or b = 2.0) and not (c = null and d != "x")`,
+		q.Format(q.And{q.Or{q.M{"a": 1}, q.M{"b": 2.0}}, q.Not{Condition: q.M{"c": nil, "d": q.NotEqual("x")}}}))
+	assert.Equal("`first name` = \"bob\"", q.Format(q.M{"first name": "bob"}))
+	// invalid conditions are printed as is instead of failing
+	assert.Equal("a = 1 and 42", q.Format(q.And{q.M{"a": 1}, 42}))
+	assert.Equal("loc within circle", q.Format(q.M{"loc": q.GeoWithin{Shape: "circle"}}))
+	// arrays and objects are not formatted as strings
+	assert.Equal(`comments = {"author": "bob", "likes": 2} and tags = ["go", "rust"]`,
+		q.Format(q.M{"tags": []string{"go", "rust"}, "comments": map[string]interface{}{"likes": 2, "author": "bob"}}))
+	filter, err := q.Parse(q.Format(q.M{"tags": []string{"go", "rust"}}))
+	ok(t, "parse array", err)
+	assert.Equal(q.M{"tags": []interface{}{"go", "rust"}}, filter)
+}
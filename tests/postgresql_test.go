@@ -7,6 +7,9 @@ import (
 	"github.com/gocontrib/log"
 	"github.com/gocontrib/nosql"
 	"github.com/gocontrib/nosql/postgresql"
+	"github.com/gocontrib/nosql/q"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPostgreStore_Basic(t *testing.T) {
@@ -33,6 +36,214 @@ func TestPostgreStore_Filters(t *testing.T) {
 	testFilters(t, store)
 }
 
+func TestPostgreStore_SortLimit(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testSortLimit(t, store)
+}
+
+func TestPostgreStore_IDGenerators(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testIDGenerators(t, store)
+}
+
+func TestPostgreStore_SwitchIDGenerator(t *testing.T) {
+	assert := assert.New(t)
+	var store = makePgStore()
+	defer store.Close()
+
+	var users = store.Collection("switchid")
+	var bob = User{Name: "bob"}
+	ok(t, "insert with native id", users.Insert(&bob))
+
+	// table with SERIAL id column already exists
+	ok(t, "set id generator", data.SetIDGenerator(store, data.UUIDv4, "switchid"))
+	users = store.Collection("switchid")
+	var rob = User{Name: "rob"}
+	ok(t, "insert with generated id", users.Insert(&rob))
+
+	var found User
+	ok(t, "get by native id", users.Get(bob.ID, &found))
+	assert.Equal("bob", found.Name)
+	ok(t, "get by generated id", users.Get(rob.ID, &found))
+	assert.Equal("rob", found.Name)
+
+	ok(t, "set id generator", data.SetIDGenerator(store, data.SequenceID, "switchid"))
+	users = store.Collection("switchid")
+	assert.Error(users.Insert(&User{Name: "alice"}), "text ids cannot be converted to native ids")
+}
+
+func TestPostgreStore_EmptyCustomID(t *testing.T) {
+	assert := assert.New(t)
+	var store = makePgStore()
+	defer store.Close()
+
+	ok(t, "set id generator", data.SetIDGenerator(store, data.IDGeneratorFunc(func(doc interface{}) (string, error) {
+		return "", nil
+	}), "emptyid"))
+	var users = store.Collection("emptyid")
+	assert.Error(users.Insert(&User{Name: "bob"}), "empty id")
+
+	count, err := users.Count()
+	ok(t, "count", err)
+	assert.Equal(int64(0), count)
+}
+
+func TestPostgreStore_NestedFields(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testNestedFields(t, store)
+}
+
+func TestPostgreStore_ArrayOperators(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testArrayOperators(t, store)
+}
+
+func TestPostgreStore_StringOperators(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testStringOperators(t, store)
+}
+
+func TestPostgreStore_NullSemantics(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testNullSemantics(t, store)
+}
+
+func TestPostgreStore_TextSearch(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testTextSearch(t, store)
+}
+
+func TestPostgreStore_GeoQueries(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testGeoQueries(t, store)
+}
+
+func TestPostgreStore_VectorSearch(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testVectorSearch(t, store)
+}
+
+func TestPostgreStore_Builder(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testBuilder(t, store)
+}
+
+func TestPostgreStore_Example(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testExample(t, store)
+}
+
+func TestPostgreStore_Explain(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testExplain(t, store)
+}
+
+func TestPostgreStore_TypedCompare(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testTypedCompare(t, store)
+}
+
+func TestPostgreStore_DeclareType(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	ok(t, "declare type", postgresql.DeclareType(store, "measures", "value", postgresql.Numeric))
+	ok(t, "declare type", postgresql.DeclareType(store, "measures", "code", postgresql.Text))
+	testTypedCompare(t, store)
+}
+
+func TestPostgreStore_StmtCache(t *testing.T) {
+	assert := assert.New(t)
+	var store = makePgStore()
+	defer store.Close()
+
+	all, err := insertTestUsers(store, 5)
+	ok(t, "insert", err)
+	var users = store.Collection("users")
+
+	// same shape of filter with other values
+	testFindAll(t, users, q.M{"name": all[1].Name}, []User{all[1]})
+	before, err := postgresql.StmtCacheStats(store)
+	ok(t, "stats", err)
+	testFindAll(t, users, q.M{"name": all[2].Name}, []User{all[2]})
+	stats, err := postgresql.StmtCacheStats(store)
+	ok(t, "stats", err)
+	assert.Equal(before.Hits+1, stats.Hits)
+	assert.Equal(before.Misses, stats.Misses)
+	assert.True(stats.Statements > 0)
+
+	// ad-hoc statements are not cached
+	_, err = users.Find(q.M{"email": all[2].Email}).Explain()
+	ok(t, "explain", err)
+	after, err := postgresql.StmtCacheStats(store)
+	ok(t, "stats", err)
+	assert.Equal(stats, after)
+
+	ok(t, "drop", postgresql.DropCollection(store, "users"))
+	stats, err = postgresql.StmtCacheStats(store)
+	ok(t, "stats", err)
+	assert.Equal(0, stats.Statements)
+
+	count, err := users.Count()
+	ok(t, "count", err)
+	assert.Equal(int64(0), count)
+}
+
+func TestPostgreStore_Containment(t *testing.T) {
+	assert := assert.New(t)
+	var store = makePgStore()
+	defer store.Close()
+
+	all, err := insertTestUsers(store, 5)
+	ok(t, "insert", err)
+	var users = store.Collection("users")
+
+	var filter = q.M{"name": q.In{all[1].Name, all[3].Name}}
+	testFindAll(t, users, filter, []User{all[1], all[3]})
+	plan, err := users.Find(filter).Explain()
+	ok(t, "explain", err)
+	assert.Contains(plan.Statement, "data @> $1::jsonb")
+	assert.Equal(4, len(plan.Args))
+}
+
+func TestPostgreStore_SortByTime(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	// times are sorted by jsonb ordering of strings unless declared
+	ok(t, "declare type", postgresql.DeclareType(store, "events", "at", postgresql.Timestamp))
+	testSortByTime(t, store)
+}
+
+func TestPostgreStore_SiblingIndexes(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testSiblingIndexes(t, store)
+}
+
+func TestPostgreStore_SiblingCollections(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testSiblingCollections(t, store)
+}
+
+func TestPostgreStore_Index(t *testing.T) {
+	var store = makePgStore()
+	defer store.Close()
+	testIndex(t, store)
+}
+
 func BenchmarkPostgreStore_Insert(b *testing.B) {
 	var store = makePgStore()
 	defer store.Close()
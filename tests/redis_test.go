@@ -33,6 +33,126 @@ func TestRedisStore_Filters(t *testing.T) {
 	testFilters(t, store)
 }
 
+func TestRedisStore_SortLimit(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testSortLimit(t, store)
+}
+
+func TestRedisStore_SortSpill(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testSortSpill(t, store)
+}
+
+func TestRedisStore_SortByID(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testSortByID(t, store)
+}
+
+func TestRedisStore_IDGenerators(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testIDGenerators(t, store)
+}
+
+func TestRedisStore_NestedFields(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testNestedFields(t, store)
+}
+
+func TestRedisStore_ArrayOperators(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testArrayOperators(t, store)
+}
+
+func TestRedisStore_StringOperators(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testStringOperators(t, store)
+}
+
+func TestRedisStore_NullSemantics(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testNullSemantics(t, store)
+}
+
+func TestRedisStore_TextSearch(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testTextSearch(t, store)
+}
+
+func TestRedisStore_GeoQueries(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testGeoQueries(t, store)
+}
+
+func TestRedisStore_VectorSearch(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testVectorSearch(t, store)
+}
+
+func TestRedisStore_Builder(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testBuilder(t, store)
+}
+
+func TestRedisStore_Example(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testExample(t, store)
+}
+
+func TestRedisStore_Explain(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testExplain(t, store)
+}
+
+func TestRedisStore_TypedCompare(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testTypedCompare(t, store)
+}
+
+func TestRedisStore_SortByTime(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testSortByTime(t, store)
+}
+
+func TestRedisStore_SiblingIndexes(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testSiblingIndexes(t, store)
+}
+
+func TestRedisStore_SiblingCollections(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testSiblingCollections(t, store)
+}
+
+func TestRedisStore_Index(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testIndex(t, store)
+}
+
+func TestRedisStore_Reindex(t *testing.T) {
+	var store = makeRedisStore()
+	defer store.Close()
+	testReindex(t, store)
+}
+
 func BenchmarkRedisStore_Insert(b *testing.B) {
 	var store = makeRedisStore()
 	defer store.Close()
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gocontrib/nosql"
+	"github.com/gocontrib/nosql/kv"
 	"github.com/gocontrib/nosql/q"
 
 	"github.com/stretchr/testify/assert"
@@ -25,6 +26,80 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
 
+type Address struct {
+	City  string `json:"city" bson:"city"`
+	Zip   string `json:"zip" bson:"zip"`
+	Floor int64  `json:"floor" bson:"floor"`
+}
+
+type Contact struct {
+	ID      string  `json:"id" bson:"_id"`
+	Name    string  `json:"name" bson:"name"`
+	Address Address `json:"address" bson:"address"`
+}
+
+type Comment struct {
+	Author string `json:"author" bson:"author"`
+	Likes  int64  `json:"likes" bson:"likes"`
+}
+
+type Post struct {
+	ID       string    `json:"id" bson:"_id"`
+	Title    string    `json:"title" bson:"title"`
+	Tags     []string  `json:"tags" bson:"tags"`
+	Scores   []int64   `json:"scores" bson:"scores"`
+	Comments []Comment `json:"comments" bson:"comments"`
+}
+
+type Paint struct {
+	ID    string  `json:"id" bson:"_id"`
+	Name  string  `json:"name" bson:"name"`
+	Color *string `json:"color" bson:"color"`
+}
+
+type Unpainted struct {
+	ID   string `json:"id" bson:"_id"`
+	Name string `json:"name" bson:"name"`
+}
+
+type Measure struct {
+	ID    string      `json:"id" bson:"_id"`
+	Name  string      `json:"name" bson:"name"`
+	Value interface{} `json:"value" bson:"value"`
+	Code  string      `json:"code" bson:"code"`
+}
+
+type Event struct {
+	ID   string    `json:"id" bson:"_id"`
+	Name string    `json:"name" bson:"name"`
+	At   time.Time `json:"at" bson:"at"`
+}
+
+type Person struct {
+	ID        string `json:"id" bson:"_id"`
+	Name      string `json:"name" bson:"name"`
+	NameFirst string `json:"name_first" bson:"name_first"`
+}
+
+type Message struct {
+	ID    string `json:"id" bson:"_id"`
+	Title string `json:"title" bson:"title"`
+	Body  string `json:"body" bson:"body"`
+}
+
+type Place struct {
+	ID       string      `json:"id" bson:"_id"`
+	Name     string      `json:"name" bson:"name"`
+	Location *data.Point `json:"location,omitempty" bson:"location,omitempty"`
+}
+
+type Embedding struct {
+	ID     string    `json:"id" bson:"_id"`
+	Name   string    `json:"name" bson:"name"`
+	Kind   string    `json:"kind" bson:"kind"`
+	Vector []float32 `json:"vector,omitempty" bson:"vector,omitempty"`
+}
+
 func ok(t *testing.T, op string, err error) {
 	if err != nil {
 		t.Errorf(op+" failed with: %v", err)
@@ -164,6 +239,832 @@ func testFilters(t *testing.T, store data.Store) {
 		q.M{"age": q.GTE(20)},
 		q.M{"age": q.LTE(25)},
 	}, []User{bob, rob})
+
+	// empty condition matches all documents, its negation none
+	count, err = users.Find(q.M{}).Count()
+	ok(t, "count by empty condition", err)
+	assert.Equal(int64(3), count)
+	count, err = users.Find(q.Not{Condition: q.M{}}).Count()
+	ok(t, "count by negation of empty condition", err)
+	assert.Equal(int64(0), count)
+}
+
+func testIndex(t *testing.T, store data.Store) {
+	var users = store.Collection("users")
+
+	// many documents with the same indexed value
+	var list []User
+	for i := 0; i < 10; i++ {
+		var u = User{
+			Name:  "bob",
+			Email: fmt.Sprintf("bob%d@mail.net", i),
+			Age:   int64(20 + i),
+		}
+		var err = users.Insert(&u)
+		ok(t, "insert", err)
+		list = append(list, u)
+	}
+
+	testFindAll(t, users, q.M{"name": "bob"}, list)
+
+	var rob = list[3]
+	rob.Name = "rob"
+	var err = users.Update(rob.ID, &rob)
+	ok(t, "update", err)
+
+	err = users.Delete(list[5].ID)
+	ok(t, "delete", err)
+
+	var bobs []User
+	for i, u := range list {
+		if i != 3 && i != 5 {
+			bobs = append(bobs, u)
+		}
+	}
+
+	testFindAll(t, users, q.M{"name": "bob"}, bobs)
+	testFindAll(t, users, q.M{"name": "rob"}, []User{rob})
+	testFindAll(t, users, q.M{"name": "bo"}, nil)
+}
+
+func testReindex(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	all, err := insertTestUsers(store, 10)
+	ok(t, "insert", err)
+
+	report, err := kv.VerifyIndexes(store, "users")
+	ok(t, "verify indexes", err)
+	assert.True(report.OK())
+	assert.Equal(4, len(report.Indexes))
+
+	err = kv.Reindex(store, "users")
+	ok(t, "reindex", err)
+
+	report, err = kv.VerifyIndexes(store, "users")
+	ok(t, "verify indexes", err)
+	assert.True(report.OK())
+	for _, i := range report.Indexes {
+		assert.Equal(int64(len(all)), i.Entries)
+	}
+
+	var users = store.Collection("users")
+	testFindAll(t, users, q.M{"name": all[3].Name}, []User{all[3]})
+	testFindAll(t, users, q.M{"email": all[5].Email}, []User{all[5]})
+}
+
+func testSortLimit(t *testing.T, store data.Store) {
+	all, err := insertTestUsers(store, 20)
+	ok(t, "insert", err)
+
+	var users = store.Collection("users")
+
+	var found []User
+	err = users.Find().Sort("-age").Limit(3).All(&found)
+	ok(t, "find sort by -age limit 3", err)
+	assertUsersOrder(t, found, []User{all[19], all[18], all[17]})
+
+	found = nil
+	err = users.Find().Sort("age").Skip(5).Limit(2).All(&found)
+	ok(t, "find sort by age skip 5 limit 2", err)
+	assertUsersOrder(t, found, []User{all[5], all[6]})
+
+	found = nil
+	err = users.Find(q.M{"age": q.LT(30)}).Sort("-age").Skip(8).All(&found)
+	ok(t, "find filter sort by -age skip 8", err)
+	assertUsersOrder(t, found, []User{all[1], all[0]})
+
+	found = nil
+	err = users.Find(q.M{"age": q.GTE(30)}).Sort("-age").Limit(100).All(&found)
+	ok(t, "find filter sort by -age limit 100", err)
+	assert.Equal(t, 10, len(found))
+
+	// indexed sort field
+	found = nil
+	err = users.Find().Sort("-name").Limit(3).All(&found)
+	ok(t, "find sort by -name limit 3", err)
+	assertUsersOrder(t, found, []User{all[8], all[7], all[6]})
+
+	found = nil
+	err = users.Find().Sort("name").Skip(1).Limit(2).All(&found)
+	ok(t, "find sort by name skip 1 limit 2", err)
+	assertUsersOrder(t, found, []User{all[9], all[10]})
+
+	found = nil
+	err = users.Find(q.M{"age": q.GTE(30)}).Sort("-name").Limit(2).All(&found)
+	ok(t, "find filter sort by -name limit 2", err)
+	assertUsersOrder(t, found, []User{all[19], all[18]})
+
+	found = nil
+	err = users.Find(q.M{"name": "user5"}).Sort("-email").All(&found)
+	ok(t, "find by name sort by -email", err)
+	assertUsersOrder(t, found, []User{all[4]})
+
+	count, err := users.Find().Sort("age").Skip(18).Limit(5).Count()
+	ok(t, "count skip 18 limit 5", err)
+	assert.Equal(t, int64(2), count)
+}
+
+func testSortSpill(t *testing.T, store data.Store) {
+	var limit = kv.SortMemoryLimit
+	kv.SortMemoryLimit = 1024
+	defer func() {
+		kv.SortMemoryLimit = limit
+	}()
+
+	var n = 111
+	all, err := insertTestUsers(store, n)
+	ok(t, "insert", err)
+
+	var expected []User
+	for i := n - 1; i >= 0; i-- {
+		expected = append(expected, all[i])
+	}
+
+	var users = store.Collection("users")
+
+	var found []User
+	err = users.Find().Sort("-age").All(&found)
+	ok(t, "find sort by -age", err)
+	assertUsersOrder(t, found, expected)
+
+	found = nil
+	err = users.Find().Sort("-age").Skip(10).Limit(50).All(&found)
+	ok(t, "find sort by -age skip 10 limit 50", err)
+	assertUsersOrder(t, found, expected[10:60])
+}
+
+func testSortByID(t *testing.T, store data.Store) {
+	var n = 111
+	all, err := insertTestUsers(store, n)
+	ok(t, "insert", err)
+
+	// keys preserve insertion order
+	var expected []User
+	for i := n - 1; i >= 0; i-- {
+		expected = append(expected, all[i])
+	}
+
+	var users = store.Collection("users")
+
+	var found []User
+	err = users.Find().Sort("-id").All(&found)
+	ok(t, "find sort by -id", err)
+	assertUsersOrder(t, found, expected)
+
+	found = nil
+	err = users.Find().Sort("-id").Limit(3).All(&found)
+	ok(t, "find sort by -id limit 3", err)
+	assertUsersOrder(t, found, expected[:3])
+
+	found = nil
+	err = users.Find(q.M{"age": q.GTE(30)}).Sort("-name").All(&found)
+	ok(t, "find filter sort by -name", err)
+	assert.Equal(t, n-10, len(found))
+	for i := 1; i < len(found); i++ {
+		assert.True(t, found[i-1].Name >= found[i].Name)
+	}
+}
+
+func testIDGenerators(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var formats = []struct {
+		gen     data.IDGenerator
+		pattern string
+	}{
+		{data.UUIDv4, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{data.UUIDv7, `^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{data.ULID, `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`},
+		{data.KSUID, `^[0-9A-Za-z]{27}$`},
+	}
+
+	for i, f := range formats {
+		var name = fmt.Sprintf("idgen%d", i)
+		ok(t, "set id generator", data.SetIDGenerator(store, f.gen, name))
+
+		var users = store.Collection(name)
+		var user = User{Name: "bob"}
+		ok(t, "insert", users.Insert(&user))
+		assert.Regexp(f.pattern, user.ID)
+
+		var found User
+		ok(t, "get by generated id", users.Get(user.ID, &found))
+		assert.Equal("bob", found.Name)
+		assert.Equal(user.ID, found.ID)
+	}
+
+	ok(t, "set id generator", data.SetIDGenerator(store, data.CallerID, "own"))
+	var users = store.Collection("own")
+
+	var alice = User{ID: "alice", Name: "Alice"}
+	ok(t, "insert with caller id", users.Insert(&alice))
+	assert.Equal("alice", alice.ID)
+
+	var found User
+	ok(t, "get by caller id", users.Get("alice", &found))
+	assert.Equal("Alice", found.Name)
+
+	found = User{}
+	ok(t, "find by caller id", users.Find(q.M{"id": "alice"}).One(&found))
+	assert.Equal("alice", found.ID)
+
+	assert.Error(users.Insert(&User{Name: "noname"}), "insert without id")
+	assert.Error(users.Insert(&User{ID: "alice", Name: "Alice"}), "insert duplicate id")
+
+	// indexes registered by failed insert are registered again
+	ok(t, "set id generator", data.SetIDGenerator(store, data.CallerID, "rollback"))
+	users = store.Collection("rollback")
+	assert.Error(users.Insert(&User{ID: "bob", Name: "bob"}, &User{Name: "noname"}), "insert without id")
+	ok(t, "insert", users.Insert(&User{ID: "rob", Name: "rob"}))
+	found = User{}
+	ok(t, "find by indexed field", users.Find(q.M{"name": "rob"}).One(&found))
+	assert.Equal("rob", found.ID)
+	plan, err := users.Find(q.M{"name": "rob"}).Explain()
+	ok(t, "explain", err)
+	if len(plan.Statement) == 0 {
+		assert.Equal("lookup", plan.Access)
+	}
+
+	// other collections keep native ids
+	var user = User{Name: "joe"}
+	ok(t, "insert", store.Collection("users").Insert(&user))
+	assert.NotEmpty(user.ID)
+}
+
+func testNestedFields(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var contacts = store.Collection("contacts")
+	var all = []Contact{
+		{Name: "bob", Address: Address{City: "Berlin", Zip: "10115", Floor: 3}},
+		{Name: "joe", Address: Address{City: "Paris", Zip: "75001", Floor: 1}},
+		{Name: "ann", Address: Address{City: "Berlin", Zip: "10117", Floor: 2}},
+	}
+	for i := range all {
+		ok(t, "insert", contacts.Insert(&all[i]))
+	}
+
+	var names = func(list []Contact) []string {
+		var a []string
+		for _, c := range list {
+			a = append(a, c.Name)
+		}
+		return a
+	}
+
+	var found []Contact
+	ok(t, "find by address.city", contacts.Find(q.M{"address.city": "Berlin"}).Sort("name").All(&found))
+	assert.Equal([]string{"ann", "bob"}, names(found))
+
+	found = nil
+	ok(t, "find by address.floor", contacts.Find(q.M{"address.floor": q.GTE(2)}).Sort("name").All(&found))
+	assert.Equal([]string{"ann", "bob"}, names(found))
+
+	found = nil
+	ok(t, "sort by -address.floor", contacts.Find().Sort("-address.floor").All(&found))
+	assert.Equal([]string{"bob", "ann", "joe"}, names(found))
+
+	found = nil
+	ok(t, "sort by address.zip", contacts.Find(q.M{"address.city": "Berlin"}).Sort("address.zip").All(&found))
+	assert.Equal([]string{"bob", "ann"}, names(found))
+
+	// object is equal exactly, not objects with more fields
+	found = nil
+	ok(t, "find by part of address", contacts.Find(q.M{"address": map[string]interface{}{"city": "Berlin"}}).All(&found))
+	assert.Empty(found)
+
+	var bob = all[0]
+	bob.Address.City = "Munich"
+	ok(t, "update", contacts.Update(bob.ID, &bob))
+
+	found = nil
+	ok(t, "find updated by address.city", contacts.Find(q.M{"address.city": "Berlin"}).All(&found))
+	assert.Equal([]string{"ann"}, names(found))
+
+	found = nil
+	ok(t, "find by new address.city", contacts.Find(q.M{"address.city": "Munich"}).All(&found))
+	assert.Equal([]string{"bob"}, names(found))
+}
+
+func testArrayOperators(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var posts = store.Collection("posts")
+	var all = []Post{
+		{Title: "p1", Tags: []string{"go", "db"}, Scores: []int64{70, 82},
+			Comments: []Comment{{Author: "ann", Likes: 7}, {Author: "bob", Likes: 1}}},
+		{Title: "p2", Tags: []string{"go", "web", "js"}, Scores: []int64{90},
+			Comments: []Comment{{Author: "ann", Likes: 3}}},
+		{Title: "p3", Tags: []string{"rust"}, Scores: []int64{85, 79},
+			Comments: []Comment{{Author: "bob", Likes: 9}}},
+	}
+	for i := range all {
+		ok(t, "insert", posts.Insert(&all[i]))
+	}
+
+	var find = func(op string, filter interface{}) []string {
+		var found []Post
+		ok(t, op, posts.Find(filter).Sort("title").All(&found))
+		var a []string
+		for _, p := range found {
+			a = append(a, p.Title)
+		}
+		return a
+	}
+
+	assert.Equal([]string{"p1", "p2"}, find("find by contained tag", q.M{"tags": "go"}))
+	assert.Equal([]string{"p3"}, find("find by not contained tag", q.M{"tags": q.NotEqual("go")}))
+	assert.Equal([]string{"p2", "p3"}, find("find by tag in", q.M{"tags": q.In{"js", "rust"}}))
+	assert.Equal([]string{"p1"}, find("find by all tags", q.M{"tags": q.All{"db", "go"}}))
+	assert.Equal([]string{"p2"}, find("find by tags size", q.M{"tags": q.Size(3)}))
+	assert.Equal([]string{"p1"}, find("find by elem match of values",
+		q.M{"scores": q.ElemMatch{Condition: q.And{q.GTE(80), q.LT(85)}}}))
+	assert.Equal([]string{"p1"}, find("find by elem match of fields",
+		q.M{"comments": q.ElemMatch{Condition: q.M{"author": "ann", "likes": q.GT(5)}}}))
+	assert.Empty(find("find by elem match of missing field",
+		q.M{"title": q.ElemMatch{Condition: q.M{"author": "ann"}}}))
+
+	// arrays and objects are equal exactly, not their supersets
+	assert.Equal([]string{"p3"}, find("find by equal array", q.M{"tags": []interface{}{"rust"}}))
+	assert.Empty(find("find by array contained in field", q.M{"tags": []interface{}{"go"}}))
+	assert.Empty(find("find by array in", q.M{"tags": q.In{[]interface{}{"go"}, []interface{}{"web"}}}))
+	assert.Empty(find("find by object contained in element", q.M{"comments": map[string]interface{}{"author": "bob"}}))
+
+	// conditions on the same array field are satisfied by any elements
+	assert.Equal([]string{"p1"}, find("find by both tags", q.And{q.M{"tags": "go"}, q.M{"tags": "db"}}))
+	assert.Equal([]string{"p1"}, find("find by disjoint ranges", q.And{q.M{"scores": q.GT(80)}, q.M{"scores": q.LT(75)}}))
+	assert.Equal([]string{"p3"}, find("find by touching ranges", q.And{q.M{"scores": q.GTE(85)}, q.M{"scores": q.LT(80)}}))
+	assert.Equal([]string{"p2"}, find("find by tags in both sets",
+		q.And{q.M{"tags": q.In{"web", "rust"}}, q.M{"tags": q.In{"js", "db"}}}))
+}
+
+func testStringOperators(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	_, err := insertTestUsers(store, 12)
+	ok(t, "insert", err)
+
+	var users = store.Collection("users")
+	ok(t, "insert", users.Insert(&User{Name: "50%_off"}, &User{Name: "50 off"}, &User{Name: "line1\nline2"}))
+
+	var find = func(op string, filter interface{}) []string {
+		var found []User
+		ok(t, op, users.Find(filter).Sort("name").All(&found))
+		var a []string
+		for _, u := range found {
+			a = append(a, u.Name)
+		}
+		return a
+	}
+
+	assert.Equal([]string{"user1", "user10", "user11", "user12"}, find("find by prefix", q.M{"name": q.Prefix("user1")}))
+	assert.Equal([]string{"50%_off"}, find("find by prefix with wildcards", q.M{"name": q.Prefix("50%")}))
+	assert.Equal([]string{"user11", "user12"}, find("find by prefix and age",
+		q.M{"name": q.Prefix("user1"), "age": q.GTE(30)}))
+	assert.Equal([]string{"user2", "user3"}, find("find by regex", q.M{"name": q.Regex("^USER[2-3]$", "i")}))
+	assert.Empty(find("find by case-sensitive regex", q.M{"name": q.Regex("^USER[2-3]$", "")}))
+	assert.Equal([]string{"user5"}, find("find by iequal", q.M{"name": q.IEqual("USER5")}))
+	assert.Empty(find("find by regex without multi-line flag", q.M{"name": q.Regex("^line2", "")}))
+	assert.Equal([]string{"line1\nline2"}, find("find by multi-line regex", q.M{"name": q.Regex("^line2", "m")}))
+
+	var found []User
+	assert.Error(users.Find(q.M{"name": q.Regex("(", "")}).All(&found), "invalid regex")
+	assert.Error(users.Find(q.M{"name": q.Regex("^user", "x")}).All(&found), "invalid regex flags")
+}
+
+// testNullSemantics holds every store to semantics of missing fields and null values described in package q.
+func testNullSemantics(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var red, blue = "red", "blue"
+	var items = store.Collection("items")
+	ok(t, "insert", items.Insert(
+		&Paint{Name: "red", Color: &red},
+		&Paint{Name: "blue", Color: &blue},
+		&Paint{Name: "null"},
+		&Unpainted{Name: "missing"},
+	))
+
+	var find = func(op string, filter interface{}) []string {
+		var found []Unpainted
+		ok(t, op, items.Find(filter).Sort("name").All(&found))
+		var a []string
+		for _, p := range found {
+			a = append(a, p.Name)
+		}
+		return a
+	}
+
+	assert.Equal([]string{"blue", "null", "red"}, find("exists", q.M{"color": q.Exists(true)}))
+	assert.Equal([]string{"missing"}, find("not exists", q.M{"color": q.Exists(false)}))
+	assert.Equal([]string{"missing", "null"}, find("is null", q.M{"color": q.IsNull()}))
+	assert.Equal([]string{"blue", "red"}, find("not null", q.M{"color": q.NotEqual(nil)}))
+	assert.Equal([]string{"red"}, find("equal", q.M{"color": "red"}))
+	assert.Equal([]string{"blue", "missing", "null"}, find("not equal", q.M{"color": q.NotEqual("red")}))
+	assert.Equal([]string{"missing", "null", "red"}, find("in with nil", q.M{"color": q.In{"red", nil}}))
+	assert.Equal([]string{"missing", "null"}, find("not in", q.M{"color": q.NotIn{"red", "blue"}}))
+	assert.Equal([]string{"blue", "red"}, find("greater", q.M{"color": q.GT("a")}))
+	assert.Equal([]string{"blue", "missing", "null"}, find("not", q.Not{Condition: q.M{"color": "red"}}))
+
+	plan, err := items.Find().Sort("color").Explain()
+	ok(t, "explain", err)
+	if len(plan.Statement) == 0 {
+		// KV stores index only strings, so documents without them are sorted in memory
+		assert.Equal("memory", plan.Sort)
+		var sorted []Unpainted
+		ok(t, "sort by color", items.Find().Sort("color").All(&sorted))
+		assert.Len(sorted, 4)
+		if len(sorted) == 4 {
+			assert.Equal("blue", sorted[2].Name)
+			assert.Equal("red", sorted[3].Name)
+		}
+
+		report, err := kv.VerifyIndexes(store, "items")
+		ok(t, "verify indexes", err)
+		assert.True(report.OK())
+		for _, i := range report.Indexes {
+			if i.Field == "color" {
+				assert.Equal(int64(2), i.Entries)
+			}
+		}
+	}
+}
+
+func testTextSearch(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	ok(t, "text index", data.TextIndex(store, "messages", "title", "body"))
+
+	var messages = store.Collection("messages")
+	var cats = Message{Title: "cats", Body: "The cat is running in the garden"}
+	var dogs = Message{Title: "dogs", Body: "Dogs like running, dogs like jumping, dogs bark at cats"}
+	var birds = Message{Title: "birds", Body: "A bird sings in the morning"}
+	var naps = Message{Title: "naps", Body: "A dog sleeps"}
+	ok(t, "insert", messages.Insert(&cats, &dogs, &birds, &naps))
+
+	var find = func(op string, result data.Result) []string {
+		var found []Message
+		ok(t, op, result.All(&found))
+		var a []string
+		for _, m := range found {
+			a = append(a, m.Title)
+		}
+		return a
+	}
+
+	assert.Equal([]string{"cats", "dogs"}, find("stemmed term", messages.Find(q.Text("runs")).Sort("title")))
+	assert.Equal([]string{"birds", "cats"}, find("any term", messages.Find(q.Text("bird garden")).Sort("title")))
+	assert.Equal([]string{"dogs"}, find("all terms", messages.Find(q.TextAll("dogs cats")).Sort("title")))
+	assert.Nil(find("stop words", messages.Find(q.Text("the"))))
+	assert.Equal([]string{"dogs", "naps"}, find("relevance", messages.Find(q.Text("dog"))))
+	assert.Equal([]string{"dogs"}, find("text and field", messages.Find(q.Text("running"), q.M{"title": "dogs"})))
+
+	count, err := messages.Find(q.Text("dog")).Count()
+	ok(t, "count", err)
+	assert.Equal(int64(2), count)
+
+	cats.Body = "The cat sleeps"
+	ok(t, "update", messages.Update(cats.ID, &cats))
+	assert.Equal([]string{"dogs"}, find("updated", messages.Find(q.Text("running"))))
+	assert.Equal([]string{"cats", "naps"}, find("updated", messages.Find(q.Text("sleeping")).Sort("title")))
+
+	ok(t, "delete", messages.Delete(dogs.ID))
+	assert.Equal([]string{"naps"}, find("deleted", messages.Find(q.Text("dog"))))
+
+	var found []Message
+	assert.Error(store.Collection("other").Find(q.Text("dog")).All(&found))
+}
+
+func testGeoQueries(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	ok(t, "geo index", data.GeoIndex(store, "places", "location"))
+
+	var places = store.Collection("places")
+	var gate = Place{Name: "gate", Location: data.NewPoint(13.3777, 52.5163)}
+	var reichstag = Place{Name: "reichstag", Location: data.NewPoint(13.3761, 52.5186)}
+	var alex = Place{Name: "alex", Location: data.NewPoint(13.4132, 52.5219)}
+	var potsdam = Place{Name: "potsdam", Location: data.NewPoint(13.0645, 52.3906)}
+	var nowhere = Place{Name: "nowhere"}
+	ok(t, "insert", places.Insert(&gate, &reichstag, &alex, &potsdam, &nowhere))
+
+	var find = func(op string, result data.Result) []string {
+		var found []Place
+		ok(t, op, result.All(&found))
+		var a []string
+		for _, p := range found {
+			a = append(a, p.Name)
+		}
+		return a
+	}
+
+	var near = q.M{"location": q.Near(13.3777, 52.5163, 500)}
+	var box = q.M{"location": q.Within(q.Box{MinLng: 13.3, MinLat: 52.5, MaxLng: 13.42, MaxLat: 52.53})}
+	var triangle = q.M{"location": q.Within(q.Polygon{{13.37, 52.51}, {13.39, 52.51}, {13.38, 52.53}})}
+
+	assert.Equal([]string{"gate", "reichstag"}, find("near", places.Find(near)))
+	assert.Equal([]string{"reichstag", "gate"}, find("near reichstag", places.Find(q.M{"location": q.Near(13.3761, 52.5186, 500)})))
+	assert.Equal([]string{"alex", "gate", "reichstag"}, find("within box", places.Find(box).Sort("name")))
+	assert.Equal([]string{"gate", "reichstag"}, find("within polygon", places.Find(triangle).Sort("name")))
+	assert.Equal([]string{"reichstag"}, find("near and field", places.Find(near, q.M{"name": q.NotEqual("gate")})))
+
+	count, err := places.Find(box).Count()
+	ok(t, "count", err)
+	assert.Equal(int64(3), count)
+
+	potsdam.Location = data.NewPoint(13.378, 52.517)
+	ok(t, "update", places.Update(potsdam.ID, &potsdam))
+	assert.Equal([]string{"gate", "potsdam", "reichstag"}, find("updated", places.Find(near).Sort("name")))
+
+	ok(t, "delete", places.Delete(reichstag.ID))
+	assert.Equal([]string{"gate", "potsdam"}, find("deleted", places.Find(near)))
+}
+
+func testVectorSearch(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	ok(t, "vector index", data.VectorIndex(store, "embeddings", "vector", 2, q.Cosine))
+
+	var embeddings = store.Collection("embeddings")
+	var a = Embedding{Name: "a", Kind: "x", Vector: []float32{1, 0}}
+	var b = Embedding{Name: "b", Kind: "y", Vector: []float32{0.9, 0.1}}
+	var c = Embedding{Name: "c", Kind: "x", Vector: []float32{0, 1}}
+	var d = Embedding{Name: "d", Kind: "y", Vector: []float32{-1, 0}}
+	var e = Embedding{Name: "e", Kind: "x", Vector: []float32{2, 2}}
+	var none = Embedding{Name: "none", Kind: "x"}
+	ok(t, "insert", embeddings.Insert(&a, &b, &c, &d, &e, &none))
+
+	var find = func(op string, result data.Result) []string {
+		var found []Embedding
+		ok(t, op, result.All(&found))
+		var names []string
+		for _, v := range found {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	var vec = []float32{1, 0}
+	var cosine = q.NearestVector("vector", vec, 3, q.Cosine)
+
+	assert.Equal([]string{"a", "b", "e"}, find("cosine", embeddings.Find(cosine)))
+	assert.Equal([]string{"a", "b", "c", "d", "e"}, find("euclidean", embeddings.Find(q.NearestVector("vector", vec, 0, q.Euclidean))))
+	assert.Equal([]string{"e", "a"}, find("dot product", embeddings.Find(q.NearestVector("vector", vec, 2, q.DotProduct))))
+	assert.Equal([]string{"a", "e"}, find("filtered", embeddings.Find(q.NearestVector("vector", vec, 2, q.Cosine), q.M{"kind": "x"})))
+	assert.Equal([]string{"b"}, find("and", embeddings.Find(q.And{q.NearestVector("vector", vec, 1, q.Euclidean), q.M{"kind": "y"}})))
+	assert.Equal([]string{"e", "b", "a"}, find("sorted", embeddings.Find(cosine).Sort("-name")))
+	assert.Equal([]string{"b", "e"}, find("paged", embeddings.Find(q.NearestVector("vector", vec, 0, q.Cosine)).Skip(1).Limit(2)))
+
+	count, err := embeddings.Find(cosine).Count()
+	ok(t, "count", err)
+	assert.Equal(int64(3), count)
+
+	var nearest Embedding
+	ok(t, "find one", embeddings.Find(cosine).One(&nearest))
+	assert.Equal("a", nearest.Name)
+
+	d.Vector = []float32{1, 0.05}
+	ok(t, "update", embeddings.Update(d.ID, &d))
+	assert.Equal([]string{"a", "d"}, find("updated", embeddings.Find(q.NearestVector("vector", vec, 2, q.Cosine))))
+
+	ok(t, "delete", embeddings.Delete(a.ID))
+	assert.Equal([]string{"d", "b"}, find("deleted", embeddings.Find(q.NearestVector("vector", vec, 2, q.Cosine))))
+}
+
+func testBuilder(t *testing.T, store data.Store) {
+	all, err := insertTestUsers(store, 10)
+	ok(t, "insert", err)
+
+	var users = store.Collection("users")
+	var cond = q.Field("age").Gte(all[2].Age).And(q.Field("age").Lt(all[6].Age)).And(
+		q.Field("name").Eq(all[3].Name).Or(q.Field("name").Eq(all[4].Name)).Not())
+	var filter = cond.MustBuild()
+	testFindAll(t, users, filter, []User{all[2], all[5]})
+}
+
+func testExample(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	all, err := insertTestUsers(store, 5)
+	ok(t, "insert", err)
+	var users = store.Collection("users")
+	testFindAll(t, users, q.Example(&User{Age: all[3].Age}), []User{all[3]})
+	testFindAll(t, users, q.Example(User{Name: all[1].Name, Age: all[1].Age}), []User{all[1]})
+
+	var contacts = store.Collection("contacts")
+	ok(t, "insert", contacts.Insert(
+		&Contact{Name: "bob", Address: Address{City: "Berlin", Floor: 3}},
+		&Contact{Name: "joe", Address: Address{City: "Paris"}},
+		&Contact{Name: "ann", Address: Address{City: "Berlin"}},
+	))
+
+	var find = func(op string, filter interface{}) []string {
+		var found []Contact
+		ok(t, op, contacts.Find(filter).Sort("name").All(&found))
+		var a []string
+		for _, c := range found {
+			a = append(a, c.Name)
+		}
+		return a
+	}
+
+	var berlin = &Contact{Address: Address{City: "Berlin"}}
+	assert.Equal([]string{"ann", "bob"}, find("nested", q.Example(berlin)))
+	assert.Equal([]string{"ann"}, find("zero", q.Example(berlin, q.IncludeZero("address.floor"))))
+	assert.Equal([]string{"joe"}, find("skip nested", q.Example(&Contact{Name: "joe", Address: Address{City: "Berlin"}}, q.SkipNested())))
+}
+
+func testExplain(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	all, err := insertTestUsers(store, 5)
+	ok(t, "insert", err)
+	var users = store.Collection("users")
+
+	var filter = q.M{"name": q.In{all[1].Name}}
+	plan, err := users.Find(filter).Sort("age").Explain()
+	ok(t, "explain", err)
+	testFindAll(t, users, filter, []User{all[1]})
+
+	if len(plan.Statement) > 0 {
+		// native query and explain of data store
+		assert.NotEmpty(plan.Explain)
+		return
+	}
+
+	assert.Equal(data.Plan{Access: "lookup", Indexes: []string{"idx_users_name"}, Sort: "memory"}, plan)
+
+	plan, err = users.Find(q.M{"age": q.GTE(all[2].Age)}).Explain()
+	ok(t, "explain scan", err)
+	assert.Equal(data.Plan{Access: "scan", Sort: "none", Filter: q.M{"age": q.GTE(all[2].Age)}}, plan)
+
+	plan, err = users.Find().Sort("-name").Explain()
+	ok(t, "explain index", err)
+	assert.Equal(data.Plan{Access: "index", Indexes: []string{"idx_users_name"}, Sort: "index"}, plan)
+
+	plan, err = users.Find(q.M{"age": 1}, q.M{"age": q.NotEqual(1)}).Explain()
+	ok(t, "explain contradiction", err)
+	assert.Equal("none", plan.Access)
+}
+
+func testSortByTime(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var day = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var events = store.Collection("events")
+	// fractions of different length and zone offsets
+	ok(t, "insert", events.Insert(
+		&Event{Name: "e4", At: day.Add(500 * time.Millisecond)},
+		&Event{Name: "e2", At: day.Add(100 * time.Millisecond)},
+		&Event{Name: "e1", At: day},
+		&Event{Name: "e3", At: day.Add(150 * time.Millisecond)},
+		&Event{Name: "e0", At: day.Add(-time.Hour).In(time.FixedZone("", 2*3600))},
+		&Event{Name: "e5", At: day.Add(time.Hour).In(time.FixedZone("", -5*3600))},
+	))
+
+	var find = func(op string, sort string) []string {
+		var found []Event
+		ok(t, op, events.Find().Sort(sort).All(&found))
+		var a []string
+		for _, e := range found {
+			a = append(a, e.Name)
+		}
+		return a
+	}
+
+	assert.Equal([]string{"e0", "e1", "e2", "e3", "e4", "e5"}, find("sort by time", "at"))
+	assert.Equal([]string{"e5", "e4", "e3", "e2", "e1", "e0"}, find("sort by time desc", "-at"))
+
+	plan, err := events.Find().Sort("at").Explain()
+	ok(t, "explain", err)
+	if len(plan.Statement) == 0 {
+		// KV stores walk index of time field
+		assert.Equal("index", plan.Access)
+
+		// rebuilt index keeps keys of times
+		ok(t, "reindex", kv.Reindex(store, "events", "at"))
+		report, err := kv.VerifyIndexes(store, "events")
+		ok(t, "verify indexes", err)
+		assert.True(report.OK())
+		assert.Equal([]string{"e0", "e1", "e2", "e3", "e4", "e5"}, find("sort by time after reindex", "at"))
+		assert.Equal([]string{"e5", "e4", "e3", "e2", "e1", "e0"}, find("sort by time desc after reindex", "-at"))
+
+		ok(t, "insert after reindex", events.Insert(&Event{Name: "e6", At: day.Add(2 * time.Hour)}))
+		assert.Equal([]string{"e0", "e1", "e2", "e3", "e4", "e5", "e6"}, find("sort by time after insert", "at"))
+	}
+}
+
+// testSiblingIndexes checks indexes of fields which name is prefixed by name of other field.
+func testSiblingIndexes(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var people = store.Collection("people")
+	ok(t, "insert", people.Insert(
+		&Person{Name: "b", NameFirst: "a"},
+		&Person{Name: "c", NameFirst: "b"},
+		&Person{Name: "a", NameFirst: "c"},
+	))
+
+	var find = func(op string, filter interface{}, sort ...string) []string {
+		var found []Person
+		ok(t, op, people.Find(filter).Sort(sort...).All(&found))
+		var a []string
+		for _, p := range found {
+			a = append(a, p.Name)
+		}
+		return a
+	}
+
+	assert.Equal([]string{"b"}, find("find by name", q.M{"name": "b"}))
+	assert.Equal([]string{"c"}, find("find by name_first", q.M{"name_first": "b"}))
+	assert.Equal([]string{"a", "b", "c"}, find("sort by name", nil, "name"))
+	assert.Equal([]string{"c", "b", "a"}, find("sort by -name", nil, "-name"))
+	assert.Equal([]string{"b", "c", "a"}, find("sort by name_first", nil, "name_first"))
+
+	plan, err := people.Find(q.M{"name": "b"}).Explain()
+	ok(t, "explain", err)
+	if len(plan.Statement) > 0 {
+		return
+	}
+
+	// rebuilding one index keeps entries of the other one
+	ok(t, "reindex", kv.Reindex(store, "people", "name"))
+	report, err := kv.VerifyIndexes(store, "people")
+	ok(t, "verify indexes", err)
+	assert.True(report.OK())
+	for _, i := range report.Indexes {
+		assert.Equal(int64(3), i.Entries, i.Field)
+	}
+	assert.Equal([]string{"c"}, find("find by name_first after reindex", q.M{"name_first": "b"}))
+	assert.Equal([]string{"b", "c", "a"}, find("sort by name_first after reindex", nil, "name_first"))
+}
+
+// testSiblingCollections checks collections which names are prefixed by other names
+// or contain special characters of patterns.
+func testSiblingCollections(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var names = map[string][]string{
+		"items":    {"a", "b"},
+		"items_1":  {"c"},
+		"items1":   {"d", "e"},
+		"items[1]": {"f"},
+	}
+	for name, list := range names {
+		var items = store.Collection(name)
+		for _, n := range list {
+			ok(t, "insert", items.Insert(&Person{Name: n}))
+		}
+	}
+
+	for name, list := range names {
+		var items = store.Collection(name)
+		count, err := items.Count()
+		ok(t, "count", err)
+		assert.Equal(int64(len(list)), count, name)
+
+		var found []Person
+		ok(t, "sort by -name", items.Find().Sort("-name").All(&found))
+		var a []string
+		for _, p := range found {
+			a = append(a, p.Name)
+		}
+		var expected []string
+		for i := len(list) - 1; i >= 0; i-- {
+			expected = append(expected, list[i])
+		}
+		assert.Equal(expected, a, name)
+	}
+}
+
+func testTypedCompare(t *testing.T, store data.Store) {
+	assert := assert.New(t)
+
+	var measures = store.Collection("measures")
+	ok(t, "insert", measures.Insert(
+		&Measure{Name: "nine", Value: 9, Code: "9"},
+		&Measure{Name: "ten", Value: 10, Code: "10"},
+		&Measure{Name: "hundred", Value: 100.5, Code: "100.5"},
+	))
+
+	var find = func(op string, filter interface{}, sort ...string) []string {
+		var found []Measure
+		ok(t, op, measures.Find(filter).Sort(sort...).All(&found))
+		var a []string
+		for _, m := range found {
+			a = append(a, m.Name)
+		}
+		return a
+	}
+
+	// numbers are compared as numbers, strings are compared as strings
+	assert.Equal([]string{"hundred", "ten"}, find("number", q.M{"value": q.GT(9)}, "name"))
+	assert.Equal([]string{"nine", "ten"}, find("number range", q.And{q.M{"value": q.GTE(9)}, q.M{"value": q.LT(50)}}, "name"))
+	assert.Equal([]string{"hundred", "ten"}, find("string", q.M{"code": q.LT("9")}, "name"))
+	assert.Equal([]string{"nine", "ten", "hundred"}, find("sort numbers", q.M{"value": q.GT(0)}, "value"))
+	assert.Equal([]string{"hundred", "ten", "nine"}, find("sort numbers desc", q.M{"value": q.GT(0)}, "-value"))
+	assert.Equal([]string{"ten", "hundred", "nine"}, find("sort strings", q.M{"code": q.GT("")}, "code"))
 }
 
 func testCursor(t *testing.T, store data.Store) {
@@ -272,6 +1173,14 @@ func assertUsers(t *testing.T, found []User, expected []User) {
 	}
 }
 
+func assertUsersOrder(t *testing.T, found []User, expected []User) {
+	assert := assert.New(t)
+	assert.Equal(len(expected), len(found))
+	for i := 0; i < len(expected) && i < len(found); i++ {
+		assertUser(t, found[i], expected[i])
+	}
+}
+
 func assertUser(t *testing.T, actual User, expected User) {
 	assert := assert.New(t)
 	assert.Equal(expected.ID, actual.ID)
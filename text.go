@@ -0,0 +1,26 @@
+package data
+
+import "errors"
+
+// TextLanguage is language of full-text search, it defines stemming and stop words.
+const TextLanguage = "english"
+
+// ErrNoTextIndex is returned by text search on collection without text fields.
+var ErrNoTextIndex = errors.New("collection has no text index")
+
+var errNoTextSearch = errors.New("store does not support full-text search")
+
+type textIndexer interface {
+	TextIndex(collection string, fields ...string) error
+}
+
+// TextIndex declares text fields of collection searched by q.Text conditions and
+// builds full-text index of them. Postgresql keeps text fields in memory,
+// so they should be declared each time the store is opened.
+func TextIndex(s Store, collection string, fields ...string) error {
+	t, ok := s.(textIndexer)
+	if !ok {
+		return errNoTextSearch
+	}
+	return t.TextIndex(collection, fields...)
+}
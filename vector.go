@@ -0,0 +1,47 @@
+package data
+
+import (
+	"errors"
+	"math"
+
+	"github.com/gocontrib/nosql/q"
+)
+
+// VectorDistance returns distance of vectors by given metric, nearer vectors have smaller distance.
+func VectorDistance(metric q.VectorMetric, a, b []float32) float64 {
+	var dot, na, nb, d float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		var x, y = float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+		d += (x - y) * (x - y)
+	}
+	switch metric {
+	case q.Euclidean:
+		return math.Sqrt(d)
+	case q.DotProduct:
+		return -dot
+	default:
+		if na == 0 || nb == 0 {
+			return 1
+		}
+		return 1 - dot/math.Sqrt(na*nb)
+	}
+}
+
+var errNoVector = errors.New("store does not support vector index")
+
+type vectorIndexer interface {
+	VectorIndex(collection, field string, dims int, metric q.VectorMetric) error
+}
+
+// VectorIndex creates index of vector field with given number of dimensions for given metric.
+// Stores find nearest vectors without index by exact search.
+func VectorIndex(s Store, collection, field string, dims int, metric q.VectorMetric) error {
+	t, ok := s.(vectorIndexer)
+	if !ok {
+		return errNoVector
+	}
+	return t.VectorIndex(collection, field, dims, metric)
+}